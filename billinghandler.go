@@ -0,0 +1,256 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// BillingConfig prices tracked time for invoices.
+type BillingConfig struct {
+	Currency string        `toml:"currency"`
+	RoundTo  time.Duration `toml:"round_to"`
+	// Rounding is up, down or nearest.
+	Rounding string `toml:"rounding"`
+	Rates    []Rate `toml:"rates"`
+}
+
+// Rate is an hourly rate for a client, project or activity from a day
+// on. Empty fields match everything; the most specific rate wins, and of
+// those the one that started last.
+type Rate struct {
+	Client   string  `toml:"client"`
+	Project  string  `toml:"project"`
+	Activity string  `toml:"activity"`
+	Hourly   float64 `toml:"hourly"`
+	From     string  `toml:"from"`
+}
+
+var Billing = BillingConfig{
+	Currency: "EUR",
+	Rounding: "up",
+}
+
+// rateFor returns the hourly rate for an activity on day.
+func rateFor(day time.Time, activity, project, client string) (float64, bool) {
+	matches := func(want, have string) bool {
+		return want == "" || strings.EqualFold(want, have)
+	}
+
+	var best *Rate
+	bestScore := -1
+	var bestFrom time.Time
+	for i, rate := range Billing.Rates {
+		if !matches(rate.Client, client) || !matches(rate.Project, project) || !matches(rate.Activity, activity) {
+			continue
+		}
+		var from time.Time
+		if rate.From != "" {
+			t, err := time.ParseInLocation("2006-01-02", rate.From, time.Local)
+			if err != nil || t.After(day) {
+				continue
+			}
+			from = t
+		}
+
+		score := 0
+		if rate.Activity != "" {
+			score += 4
+		}
+		if rate.Project != "" {
+			score += 2
+		}
+		if rate.Client != "" {
+			score++
+		}
+		if score > bestScore || (score == bestScore && from.After(bestFrom)) {
+			best, bestScore, bestFrom = &Billing.Rates[i], score, from
+		}
+	}
+	if best == nil {
+		return 0, false
+	}
+	return best.Hourly, true
+}
+
+// roundBilled rounds tracked time to the billing increment.
+func roundBilled(d time.Duration) time.Duration {
+	step := Billing.RoundTo
+	if step <= 0 {
+		return d
+	}
+	switch Billing.Rounding {
+	case "down":
+		return d.Truncate(step)
+	case "nearest":
+		return d.Round(step)
+	default:
+		if rounded := d.Truncate(step); rounded < d {
+			return rounded + step
+		}
+		return d
+	}
+}
+
+// invoiceLine is one billed session.
+type invoiceLine struct {
+	Date     time.Time
+	Activity string
+	Project  string
+	Client   string
+	Tracked  time.Duration
+	Billed   time.Duration
+	Rate     float64
+	Rated    bool
+	Amount   float64
+}
+
+// buildInvoice prices the sessions between from and to for client, or
+// for every client when it is empty.
+func buildInvoice(from, to time.Time, client string) ([]invoiceLine, error) {
+	sessions, err := readSessions(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	activities := loadActivities()
+	var lines []invoiceLine
+	for _, session := range sessions {
+		activity := activities[session.Name]
+		if activity.Project == "" {
+			activity.Project = ruleProject(session.Name)
+		}
+		if client != "" && !strings.EqualFold(activity.Client, client) {
+			continue
+		}
+
+		line := invoiceLine{
+			Date:     session.Start,
+			Activity: session.Name,
+			Project:  activity.Project,
+			Client:   activity.Client,
+			Tracked:  session.Duration,
+			Billed:   roundBilled(session.Duration),
+		}
+		line.Rate, line.Rated = rateFor(session.Start, session.Name, activity.Project, activity.Client)
+		line.Amount = math.Round(line.Billed.Hours()*line.Rate*100) / 100
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// writeInvoice writes an itemised invoice workbook.
+func writeInvoice(w io.Writer, lines []invoiceLine, number, client string, from, to time.Time) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	const sheet = "Invoice"
+	f.SetSheetName("Sheet1", sheet)
+	hours, _ := f.NewStyle(&excelize.Style{CustomNumFmt: stringPtr("0.00")})
+	money, _ := f.NewStyle(&excelize.Style{CustomNumFmt: stringPtr("#,##0.00 \"" + Billing.Currency + "\"")})
+	bold, _ := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+
+	title := "Invoice"
+	if number != "" {
+		title += " " + number
+	}
+	f.SetCellValue(sheet, "A1", title)
+	f.SetCellStyle(sheet, "A1", "A1", bold)
+	f.SetCellValue(sheet, "A2", "Client")
+	f.SetCellValue(sheet, "B2", client)
+	f.SetCellValue(sheet, "A3", "Period")
+	f.SetCellValue(sheet, "B3", from.Format("2006-01-02")+" to "+to.Format("2006-01-02"))
+	f.SetCellValue(sheet, "A4", "Date")
+	f.SetCellValue(sheet, "B4", time.Now().Format("2006-01-02"))
+
+	header := []any{"Date", "Activity", "Project", "Tracked hours", "Billed hours", "Rate", "Amount"}
+	f.SetSheetRow(sheet, "A6", &header)
+	f.SetCellStyle(sheet, "A6", "G6", bold)
+
+	row := 7
+	for _, line := range lines {
+		f.SetSheetRow(sheet, fmt.Sprintf("A%d", row), &[]any{
+			line.Date.Format("2006-01-02"),
+			line.Activity,
+			line.Project,
+			math.Round(line.Tracked.Hours()*100) / 100,
+			line.Billed.Hours(),
+			line.Rate,
+			line.Amount,
+		})
+		row++
+	}
+	if len(lines) > 0 {
+		f.SetCellStyle(sheet, "D7", fmt.Sprintf("E%d", row-1), hours)
+		f.SetCellStyle(sheet, "F7", fmt.Sprintf("G%d", row-1), money)
+	}
+
+	f.SetCellValue(sheet, fmt.Sprintf("A%d", row+1), "Total")
+	f.SetCellFormula(sheet, fmt.Sprintf("E%d", row+1), fmt.Sprintf("SUM(E7:E%d)", row))
+	f.SetCellFormula(sheet, fmt.Sprintf("G%d", row+1), fmt.Sprintf("SUM(G7:G%d)", row))
+	f.SetCellStyle(sheet, fmt.Sprintf("A%d", row+1), fmt.Sprintf("G%d", row+1), bold)
+	f.SetCellStyle(sheet, fmt.Sprintf("E%d", row+1), fmt.Sprintf("E%d", row+1), hours)
+	f.SetCellStyle(sheet, fmt.Sprintf("G%d", row+1), fmt.Sprintf("G%d", row+1), money)
+	f.SetColWidth(sheet, "B", "B", 40)
+	f.SetColWidth(sheet, "C", "C", 20)
+	f.SetColWidth(sheet, "D", "G", 14)
+
+	return f.Write(w)
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+// unratedActivities lists the activities billed without a rate.
+func unratedActivities(lines []invoiceLine) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, line := range lines {
+		if !line.Rated && !seen[line.Activity] {
+			seen[line.Activity] = true
+			names = append(names, line.Activity)
+		}
+	}
+	return names
+}
+
+func invoiceCommand(args []string) error {
+	flags := flag.NewFlagSet("invoice", flag.ContinueOnError)
+	fromText := flags.String("from", "", "first day to bill (YYYY-MM-DD)")
+	toText := flags.String("to", "", "last day to bill (YYYY-MM-DD)")
+	client := flags.String("client", "", "only activities of this client")
+	number := flags.String("number", "", "invoice number")
+	output := flags.String("o", "invoice.xlsx", "file to write")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	from, to, err := parseDateRange(*fromText, *toText)
+	if err != nil {
+		return err
+	}
+	lines, err := buildInvoice(from, to, *client)
+	if err != nil {
+		return err
+	}
+	if len(lines) == 0 {
+		return fmt.Errorf("no sessions to bill")
+	}
+	if unrated := unratedActivities(lines); len(unrated) > 0 {
+		fmt.Fprintf(os.Stderr, "No rate for: %s\n", strings.Join(unrated, ", "))
+	}
+
+	out, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return writeInvoice(out, lines, *number, *client, from, to)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateFor(t *testing.T) {
+	old := Billing
+	t.Cleanup(func() { Billing = old })
+	Billing.Rates = []Rate{
+		{Hourly: 50},
+		{Client: "Acme", Hourly: 80},
+		{Client: "Acme", Hourly: 90, From: "2024-03-01"},
+		{Client: "Acme", Project: "Site", Hourly: 100},
+		{Activity: "Support", Hourly: 60},
+	}
+
+	tests := []struct {
+		day                       string
+		activity, project, client string
+		want                      float64
+	}{
+		{"2024-02-01", "Code", "", "Other", 50},
+		{"2024-02-01", "Code", "", "Acme", 80},
+		{"2024-03-02", "Code", "", "acme", 90},
+		{"2024-03-02", "Code", "Site", "Acme", 100},
+		{"2024-03-02", "Support", "Site", "Acme", 60},
+	}
+	for _, test := range tests {
+		day, _ := time.ParseInLocation("2006-01-02", test.day, time.Local)
+		got, ok := rateFor(day, test.activity, test.project, test.client)
+		if !ok || got != test.want {
+			t.Errorf("rateFor(%s, %q, %q, %q) = %g, %v, want %g", test.day, test.activity, test.project,
+				test.client, got, ok, test.want)
+		}
+	}
+
+	Billing.Rates = nil
+	if _, ok := rateFor(time.Now(), "Code", "", ""); ok {
+		t.Error("found a rate with none configured")
+	}
+}
+
+func TestRoundBilled(t *testing.T) {
+	old := Billing
+	t.Cleanup(func() { Billing = old })
+
+	tests := []struct {
+		rounding string
+		roundTo  time.Duration
+		in, want time.Duration
+	}{
+		{"up", 15 * time.Minute, 31 * time.Minute, 45 * time.Minute},
+		{"up", 15 * time.Minute, 30 * time.Minute, 30 * time.Minute},
+		{"down", 15 * time.Minute, 44 * time.Minute, 30 * time.Minute},
+		{"nearest", 15 * time.Minute, 38 * time.Minute, 45 * time.Minute},
+		{"nearest", 15 * time.Minute, 37 * time.Minute, 30 * time.Minute},
+		{"up", 0, 37 * time.Minute, 37 * time.Minute},
+	}
+	for _, test := range tests {
+		Billing.Rounding, Billing.RoundTo = test.rounding, test.roundTo
+		if got := roundBilled(test.in); got != test.want {
+			t.Errorf("%s to %s: roundBilled(%s) = %s, want %s", test.rounding, test.roundTo, test.in, got, test.want)
+		}
+	}
+}
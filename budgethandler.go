@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+var (
+	SessionTarget time.Duration
+
+	activeActivity Activity
+	usedToday      time.Duration
+	usedWeek       time.Duration
+	budgetAlerted  bool
+
+	budgetLabel *widget.Label
+	budgetBar   *widget.ProgressBar
+)
+
+// budgetLimit is one limit that applies to the running session.
+type budgetLimit struct {
+	name  string
+	limit time.Duration
+	used  time.Duration
+}
+
+// weekStart returns midnight on the Monday of t's week.
+func weekStart(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	day := t.AddDate(0, 0, -offset)
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// loadBudget reads the budget of the activity being started and how much
+// of it has already been used. It is called when the timer starts.
+func loadBudget() {
+	now := time.Now()
+	activeActivity, _ = loadActivity(nameEntry.Text)
+	activeActivity.Name = nameEntry.Text
+	usedToday = trackedTime(activeActivity.Name, now, now)
+	usedWeek = trackedTime(activeActivity.Name, weekStart(now), now)
+	budgetAlerted = false
+	tickBudget()
+}
+
+// budgetLimits lists the limits that are set for the running session.
+func budgetLimits() []budgetLimit {
+	var limits []budgetLimit
+	if SessionTarget > 0 {
+		limits = append(limits, budgetLimit{"Target", SessionTarget, Elapsed})
+	}
+	if activeActivity.DailyBudget > 0 {
+		limits = append(limits, budgetLimit{"Daily budget", activeActivity.DailyBudget, usedToday + Elapsed})
+	}
+	if activeActivity.WeeklyBudget > 0 {
+		limits = append(limits, budgetLimit{"Weekly budget", activeActivity.WeeklyBudget, usedWeek + Elapsed})
+	}
+	return limits
+}
+
+// tickBudget is called from the update loop. It shows the limit with the
+// least time left and alerts once when it runs out.
+func tickBudget() {
+	if budgetLabel == nil {
+		return
+	}
+
+	limits := budgetLimits()
+	if !state.running || len(limits) == 0 {
+		budgetLabel.SetText("")
+		budgetBar.Hide()
+		return
+	}
+
+	tightest := limits[0]
+	for _, l := range limits[1:] {
+		if l.limit-l.used < tightest.limit-tightest.used {
+			tightest = l
+		}
+	}
+
+	remaining := tightest.limit - tightest.used
+	budgetBar.Show()
+	budgetBar.SetValue(min(float64(tightest.used)/float64(tightest.limit), 1))
+	if remaining > 0 {
+		budgetLabel.SetText(fmt.Sprintf("%s: %s left", tightest.name, formatDuration(remaining)))
+		return
+	}
+
+	budgetLabel.SetText(fmt.Sprintf("%s reached (+%s)", tightest.name, formatDuration(-remaining)))
+	if !budgetAlerted {
+		budgetAlerted = true
+		alertBudget(tightest)
+	}
+}
+
+// alertBudget brings up a window saying which limit was reached.
+func alertBudget(l budgetLimit) {
+	w := windowMaker(App, "Budget")
+	message := widget.NewLabel(fmt.Sprintf("%s of %s reached for %q.",
+		l.name, formatDuration(l.limit), activeActivity.Name))
+	w.SetContent(container.NewVBox(message, button("OK", w.Close)))
+	w.RequestFocus()
+	w.Show()
+}
+
+// showBudgetSettings edits the session target and the budgets of the
+// activity in nameEntry, all in minutes.
+func showBudgetSettings() {
+	w := windowMaker(App, "Budget")
+
+	activity, _ := loadActivity(nameEntry.Text)
+	activity.Name = nameEntry.Text
+
+	targetEntry := widget.NewEntry()
+	targetEntry.SetText(strconv.Itoa(int(SessionTarget.Minutes())))
+	dailyEntry := widget.NewEntry()
+	dailyEntry.SetText(strconv.Itoa(int(activity.DailyBudget.Minutes())))
+	weeklyEntry := widget.NewEntry()
+	weeklyEntry.SetText(strconv.Itoa(int(activity.WeeklyBudget.Minutes())))
+
+	saveButton := button("Save", func() {
+		target, err1 := strconv.Atoi(targetEntry.Text)
+		daily, err2 := strconv.Atoi(dailyEntry.Text)
+		weekly, err3 := strconv.Atoi(weeklyEntry.Text)
+		if err1 != nil || err2 != nil || err3 != nil || target < 0 || daily < 0 || weekly < 0 {
+			slog.Warn("Invalid budget")
+			return
+		}
+
+		SessionTarget = time.Duration(target) * time.Minute
+		if activity.Name != "" {
+			activity.DailyBudget = time.Duration(daily) * time.Minute
+			activity.WeeklyBudget = time.Duration(weekly) * time.Minute
+			if err := saveActivity(activity); err != nil {
+				showSaveError("Error saving activity", err)
+				return
+			}
+			slog.Info("Activity saved.")
+		}
+		if state.running && activity.Name == activeActivity.Name {
+			activeActivity = activity
+			budgetAlerted = false
+		}
+		w.Close()
+	})
+
+	w.SetContent(container.NewVBox(
+		widget.NewLabel(fmt.Sprintf("Activity: %s", activity.Name)),
+		widget.NewForm(
+			widget.NewFormItem("Target (min)", targetEntry),
+			widget.NewFormItem("Daily budget (min)", dailyEntry),
+			widget.NewFormItem("Weekly budget (min)", weeklyEntry),
+		),
+		saveButton,
+	))
+	w.Show()
+}
@@ -1,8 +1,7 @@
 package main
 
 func exitApp() {
-	logEvent("EXIT")
-	defer App.Quit()
+	shutdown()
 }
 
 func stopTimer() {
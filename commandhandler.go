@@ -0,0 +1,211 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// commands are run from the command line instead of opening the window,
+// as in "timer export-csv -from 2025-01-01".
+var commands = map[string]func(args []string) error{
+	"export-csv":      exportCSVCommand,
+	"export-ics":      exportICSCommand,
+	"export-json":     exportJSONCommand,
+	"export-ledger":   exportLedgerCommand,
+	"export-org":      exportOrgCommand,
+	"export-timew":    exportTimewCommand,
+	"import-clockify": importTogglCommand,
+	"import-csv":      importCSVCommand,
+	"import-ics":      importICSCommand,
+	"import-timew":    importTimewCommand,
+	"import-toggl":    importTogglCommand,
+	"invoice":         invoiceCommand,
+	"serve":           serveCommand,
+	"timesheet":       timesheetCommand,
+	"webhook-listen":  webhookListenCommand,
+	"webhook-test":    webhookTestCommand,
+}
+
+// runCommand runs the command named by args[0].
+func runCommand(args []string) error {
+	command, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	return command(args[1:])
+}
+
+// parseDateRange reads the dates of a range as YYYY-MM-DD. An empty from
+// is the first day in the workbook and an empty to is today.
+func parseDateRange(fromText, toText string) (time.Time, time.Time, error) {
+	to := time.Now()
+	if toText != "" {
+		t, err := time.ParseInLocation("2006-01-02", toText, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q", toText)
+		}
+		to = t
+	}
+
+	from := to
+	if fromText != "" {
+		t, err := time.ParseInLocation("2006-01-02", fromText, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q", fromText)
+		}
+		from = t
+	} else if days, err := sheetDays(); err == nil && len(days) > 0 {
+		from = days[0]
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("range starts after it ends")
+	}
+	return from, to, nil
+}
+
+// sessionFlags are the flags shared by the session exports.
+type sessionFlags struct {
+	from, to, activity, tag, output *string
+}
+
+// sessionFilterFlags adds -from, -to, -activity, -tag and -o to flags.
+// output is the default file for -o; empty means stdout.
+func sessionFilterFlags(flags *flag.FlagSet, output string) *sessionFlags {
+	outputUsage := "file to write, stdout if empty"
+	if output != "" {
+		outputUsage = "file to write"
+	}
+	return &sessionFlags{
+		from:     flags.String("from", "", "first day to export (YYYY-MM-DD)"),
+		to:       flags.String("to", "", "last day to export (YYYY-MM-DD)"),
+		activity: flags.String("activity", "", "only this activity"),
+		tag:      flags.String("tag", "", "only activities with this #tag"),
+		output:   flags.String("o", output, outputUsage),
+	}
+}
+
+// open reads the parsed flags into a query and opens the file to write.
+func (f *sessionFlags) open() (sessionQuery, io.WriteCloser, error) {
+	from, to, err := parseDateRange(*f.from, *f.to)
+	if err != nil {
+		return sessionQuery{}, nil, err
+	}
+	out, err := outputFile(*f.output)
+	if err != nil {
+		return sessionQuery{}, nil, err
+	}
+	return sessionQuery{From: from, To: to, Filter: exportFilter{Activity: *f.activity, Tag: *f.tag}}, out, nil
+}
+
+// outputFile opens name for writing, or stdout for "" and "-".
+func outputFile(name string) (io.WriteCloser, error) {
+	if name == "" || name == "-" {
+		return nopCloser{os.Stdout}, nil
+	}
+	return os.Create(name)
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// importEntries adds entries to the workbook, or only reports them when
+// dryRun is set.
+func importEntries(entries []TimerEntry, dryRun bool, out io.Writer) error {
+	if dryRun {
+		for _, entry := range entries {
+			fmt.Fprintf(out, "%s\t%s\t%s\t%s\n", entry.Timestamp.Format(time.RFC3339),
+				entry.Event, entry.Name, formatDuration(entry.Duration))
+		}
+		fmt.Fprintf(out, "%d entries would be imported\n", len(entries))
+		return nil
+	}
+
+	added, skipped, err := appendEntries(entries)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Imported %d entries, skipped %d duplicates\n", added, skipped)
+	return nil
+}
+
+func exportCSVCommand(args []string) error {
+	flags := flag.NewFlagSet("export-csv", flag.ContinueOnError)
+	fromText := flags.String("from", "", "first day to export (YYYY-MM-DD)")
+	toText := flags.String("to", "", "last day to export (YYYY-MM-DD)")
+	sessions := flags.Bool("sessions", false, "export sessions instead of events")
+	delimiterText := flags.String("delimiter", ",", "field delimiter")
+	output := flags.String("o", "", "file to write, stdout if empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	from, to, err := parseDateRange(*fromText, *toText)
+	if err != nil {
+		return err
+	}
+	delimiter, err := parseDelimiter(*delimiterText)
+	if err != nil {
+		return err
+	}
+
+	out, err := outputFile(*output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return exportCSV(out, from, to, *sessions, delimiter)
+}
+
+// exportCSV writes the events or sessions between from and to as CSV.
+func exportCSV(w io.Writer, from, to time.Time, sessions bool, delimiter rune) error {
+	entries, err := readEntries(from, to)
+	if err != nil {
+		return err
+	}
+	if sessions {
+		return writeSessionsCSV(w, buildSessions(entries), delimiter)
+	}
+	return writeEventsCSV(w, entries, delimiter)
+}
+
+func importCSVCommand(args []string) error {
+	flags := flag.NewFlagSet("import-csv", flag.ContinueOnError)
+	delimiterText := flags.String("delimiter", ",", "field delimiter")
+	mappingText := flags.String("map", "", "column mapping as field=column,... for timestamp, event, activity, duration, start and end")
+	dryRun := flags.Bool("dry-run", false, "show what would be imported without writing")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: import-csv [flags] FILE")
+	}
+
+	delimiter, err := parseDelimiter(*delimiterText)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	header, records, err := readCSV(file, delimiter)
+	if err != nil {
+		return err
+	}
+	mapping, err := parseMapping(*mappingText, header)
+	if err != nil {
+		return err
+	}
+	entries, err := csvEntries(header, records, mapping)
+	if err != nil {
+		return err
+	}
+	return importEntries(entries, *dryRun, os.Stdout)
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/BurntSushi/toml"
+)
+
+// Config is the per-user config file. Every field has a default, so the
+// file only needs the settings that differ.
+type Config struct {
+	Storage  StorageConfig  `toml:"storage"`
+	Timer    TimerConfig    `toml:"timer"`
+	UI       UIConfig       `toml:"ui"`
+	Exit     ExitConfig     `toml:"exit"`
+	Sync     SyncConfig     `toml:"sync"`
+	Pomodoro PomodoroConfig `toml:"pomodoro"`
+	Reminder ReminderRules  `toml:"reminders"`
+	Hooks    []Hook         `toml:"hooks"`
+	Projects []ProjectRule  `toml:"projects"`
+	Billing  BillingConfig  `toml:"billing"`
+	Server   ServerConfig   `toml:"server"`
+	Webhooks []Webhook      `toml:"webhooks"`
+}
+
+type StorageConfig struct {
+	DataDir   string `toml:"data_dir"`
+	ExcelFile string `toml:"excel_file"`
+}
+
+type TimerConfig struct {
+	RefreshRate   time.Duration `toml:"refresh_rate"`
+	IdleThreshold time.Duration `toml:"idle_threshold"`
+}
+
+type UIConfig struct {
+	WindowWidth   float32       `toml:"window_width"`
+	WindowHeight  float32       `toml:"window_height"`
+	FixedSize     bool          `toml:"fixed_size"`
+	LogResetDelay time.Duration `toml:"log_reset_delay"`
+	Pomodoro      bool          `toml:"pomodoro"`
+}
+
+type ExitConfig struct {
+	HookTimeout  time.Duration `toml:"hook_timeout"`
+	HookDeadline time.Duration `toml:"hook_deadline"`
+	SyncDeadline time.Duration `toml:"sync_deadline"`
+}
+
+type PomodoroConfig struct {
+	Work       time.Duration `toml:"work"`
+	ShortBreak time.Duration `toml:"short_break"`
+	LongBreak  time.Duration `toml:"long_break"`
+	LongEvery  int           `toml:"long_every"`
+}
+
+var (
+	WindowWidth   float32 = 400
+	WindowHeight  float32 = 300
+	FixedSize             = true
+	LogResetDelay         = 6 * time.Second
+	StartPomodoro         = false
+
+	configFileName string
+)
+
+// configPath returns the config file in the user's config directory,
+// $XDG_CONFIG_HOME/timer/config.toml on Linux.
+func configPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "timer", "config.toml"), nil
+}
+
+// currentConfig collects the settings in use.
+func currentConfig() Config {
+	return Config{
+		Storage: StorageConfig{
+			DataDir:   DataDir,
+			ExcelFile: excelFileName,
+		},
+		Timer: TimerConfig{
+			RefreshRate:   WaitDuration,
+			IdleThreshold: IdleThreshold,
+		},
+		UI: UIConfig{
+			WindowWidth:   WindowWidth,
+			WindowHeight:  WindowHeight,
+			FixedSize:     FixedSize,
+			LogResetDelay: LogResetDelay,
+			Pomodoro:      StartPomodoro,
+		},
+		Exit: ExitConfig{
+			HookTimeout:  HookTimeout,
+			HookDeadline: ExitHookDeadline,
+			SyncDeadline: ExitSyncDeadline,
+		},
+		Sync: Sync,
+		Pomodoro: PomodoroConfig{
+			Work:       PomodoroWork,
+			ShortBreak: PomodoroShortBreak,
+			LongBreak:  PomodoroLongBreak,
+			LongEvery:  PomodoroLongEvery,
+		},
+		Reminder: Reminders,
+		Hooks:    Hooks,
+		Projects: ProjectRules,
+		Billing:  Billing,
+		Server:   Server,
+		Webhooks: Webhooks,
+	}
+}
+
+// applyConfig puts cfg into use. Zero values keep the current setting,
+// except for the data directory, where empty means the default. The
+// error reports project rules that do not compile; the rest is applied.
+func applyConfig(cfg Config) error {
+	DataDir = cfg.Storage.DataDir
+	if cfg.Storage.ExcelFile != "" {
+		excelFileName = cfg.Storage.ExcelFile
+	}
+	if cfg.Timer.RefreshRate > 0 {
+		WaitDuration = cfg.Timer.RefreshRate
+	}
+	if cfg.Timer.IdleThreshold > 0 {
+		IdleThreshold = cfg.Timer.IdleThreshold
+	}
+	if cfg.UI.WindowWidth > 0 && cfg.UI.WindowHeight > 0 {
+		WindowWidth = cfg.UI.WindowWidth
+		WindowHeight = cfg.UI.WindowHeight
+	}
+	FixedSize = cfg.UI.FixedSize
+	if cfg.UI.LogResetDelay > 0 {
+		LogResetDelay = cfg.UI.LogResetDelay
+	}
+	StartPomodoro = cfg.UI.Pomodoro
+	if cfg.Exit.HookTimeout > 0 {
+		HookTimeout = cfg.Exit.HookTimeout
+	}
+	if cfg.Exit.HookDeadline > 0 {
+		ExitHookDeadline = cfg.Exit.HookDeadline
+	}
+	if cfg.Exit.SyncDeadline > 0 {
+		ExitSyncDeadline = cfg.Exit.SyncDeadline
+	}
+	Sync = cfg.Sync
+	if Sync.Remote == "" {
+		Sync.Remote = "origin"
+	}
+	if Sync.Timeout <= 0 {
+		Sync.Timeout = 30 * time.Second
+	}
+	if cfg.Pomodoro.Work > 0 {
+		PomodoroWork = cfg.Pomodoro.Work
+	}
+	if cfg.Pomodoro.ShortBreak > 0 {
+		PomodoroShortBreak = cfg.Pomodoro.ShortBreak
+	}
+	if cfg.Pomodoro.LongBreak > 0 {
+		PomodoroLongBreak = cfg.Pomodoro.LongBreak
+	}
+	PomodoroLongEvery = cfg.Pomodoro.LongEvery
+	Reminders = cfg.Reminder
+	Hooks = cfg.Hooks
+	var err error
+	ProjectRules, err = compileProjectRules(cfg.Projects)
+	Billing = cfg.Billing
+	if Billing.Rounding == "" {
+		Billing.Rounding = "up"
+	}
+	Webhooks = cfg.Webhooks
+	Server = cfg.Server
+	if Server.Address == "" {
+		Server.Address = "127.0.0.1:8377"
+	}
+	return err
+}
+
+// loadConfig reads the config file at startup, writing one with the
+// defaults if there is none yet.
+func loadConfig() error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	configFileName = path
+
+	cfg := currentConfig()
+	if _, err := toml.DecodeFile(path, &cfg); err != nil {
+		if os.IsNotExist(err) {
+			return saveConfig()
+		}
+		return err
+	}
+	return applyConfig(cfg)
+}
+
+// saveConfig writes the settings in use to the config file.
+func saveConfig() error {
+	if configFileName == "" {
+		return fmt.Errorf("no config file")
+	}
+	if err := os.MkdirAll(filepath.Dir(configFileName), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(configFileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return toml.NewEncoder(file).Encode(currentConfig())
+}
+
+// persistConfig saves the config file and reports a failure in LogEntry.
+func persistConfig() {
+	if err := saveConfig(); err != nil {
+		slog.Error("Error saving config", "err", err)
+	}
+}
+
+// showSettings edits the general settings. Window size applies to
+// windows opened afterwards.
+func showSettings() {
+	w := windowMaker(App, "Settings")
+
+	dirEntry := widget.NewEntry()
+	dirEntry.SetText(DataDir)
+	dirEntry.SetPlaceHolder(defaultDataDir())
+	fileEntry := widget.NewEntry()
+	fileEntry.SetText(excelFileName)
+	refreshEntry := widget.NewEntry()
+	refreshEntry.SetText(WaitDuration.String())
+	idleEntry := widget.NewEntry()
+	idleEntry.SetText(IdleThreshold.String())
+	logDelayEntry := widget.NewEntry()
+	logDelayEntry.SetText(LogResetDelay.String())
+	widthEntry := widget.NewEntry()
+	widthEntry.SetText(strconv.Itoa(int(WindowWidth)))
+	heightEntry := widget.NewEntry()
+	heightEntry.SetText(strconv.Itoa(int(WindowHeight)))
+	fixedCheck := widget.NewCheck("Fixed window size", nil)
+	fixedCheck.SetChecked(FixedSize)
+	pomodoroCheck := widget.NewCheck("Start in Pomodoro mode", nil)
+	pomodoroCheck.SetChecked(StartPomodoro)
+	syncCheck := widget.NewCheck("Commit to git on exit", nil)
+	syncCheck.SetChecked(Sync.Enabled)
+	pushCheck := widget.NewCheck("Push after commit", nil)
+	pushCheck.SetChecked(Sync.Push)
+	repoEntry := widget.NewEntry()
+	repoEntry.SetText(Sync.Repository)
+	repoEntry.SetPlaceHolder("Data directory")
+
+	saveButton := button("Save", func() {
+		refresh, err1 := time.ParseDuration(refreshEntry.Text)
+		idle, err2 := time.ParseDuration(idleEntry.Text)
+		logDelay, err3 := time.ParseDuration(logDelayEntry.Text)
+		width, err4 := strconv.Atoi(widthEntry.Text)
+		height, err5 := strconv.Atoi(heightEntry.Text)
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil || err5 != nil ||
+			fileEntry.Text == "" || refresh <= 0 || idle <= 0 || logDelay <= 0 || width <= 0 || height <= 0 {
+			slog.Warn("Invalid settings")
+			return
+		}
+
+		cfg := currentConfig()
+		cfg.Storage.DataDir = dirEntry.Text
+		cfg.Storage.ExcelFile = fileEntry.Text
+		cfg.Timer.RefreshRate = refresh
+		cfg.Timer.IdleThreshold = idle
+		cfg.UI.LogResetDelay = logDelay
+		cfg.UI.WindowWidth = float32(width)
+		cfg.UI.WindowHeight = float32(height)
+		cfg.UI.FixedSize = fixedCheck.Checked
+		cfg.UI.Pomodoro = pomodoroCheck.Checked
+		cfg.Sync.Enabled = syncCheck.Checked
+		cfg.Sync.Push = pushCheck.Checked
+		cfg.Sync.Repository = repoEntry.Text
+		if err := applyConfig(cfg); err != nil {
+			slog.Error("Error in config", "err", err)
+		}
+		if err := initDataDir(); err != nil {
+			slog.Error("Error preparing data directory", "err", err)
+		}
+		initExcelFile()
+		persistConfig()
+		w.Close()
+	})
+
+	w.SetContent(container.NewVBox(
+		widget.NewForm(
+			widget.NewFormItem("Data directory", dirEntry),
+			widget.NewFormItem("Excel file", fileEntry),
+			widget.NewFormItem("Refresh rate", refreshEntry),
+			widget.NewFormItem("Idle after", idleEntry),
+			widget.NewFormItem("Clear log after", logDelayEntry),
+			widget.NewFormItem("Window width", widthEntry),
+			widget.NewFormItem("Window height", heightEntry),
+			widget.NewFormItem("Git repository", repoEntry),
+		),
+		fixedCheck,
+		pomodoroCheck,
+		syncCheck,
+		pushCheck,
+		saveButton,
+	))
+	w.Show()
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// csvFields are the columns an import can map. Either timestamp and
+// event are mapped, for event rows, or start and end, for session rows.
+var csvFields = []string{"timestamp", "event", "activity", "duration", "start", "end"}
+
+var csvAliases = map[string][]string{
+	"timestamp": {"timestamp", "time", "date"},
+	"event":     {"event", "type"},
+	"activity":  {"activity", "activity name", "name", "description"},
+	"duration":  {"duration", "duration_seconds", "seconds"},
+	"start":     {"start", "start time", "started"},
+	"end":       {"end", "end time", "stop", "stopped"},
+}
+
+// parseDelimiter reads a one-character delimiter; "\t" and "tab" mean a
+// tab.
+func parseDelimiter(text string) (rune, error) {
+	if text == "" {
+		return ',', nil
+	}
+	if text == `\t` || strings.EqualFold(text, "tab") {
+		return '\t', nil
+	}
+	r, size := utf8.DecodeRuneInString(text)
+	if size != len(text) || r == '"' || r == '\r' || r == '\n' {
+		return 0, fmt.Errorf("invalid delimiter %q", text)
+	}
+	return r, nil
+}
+
+// writeEventsCSV writes one row per event.
+func writeEventsCSV(w io.Writer, entries []TimerEntry, delimiter rune) error {
+	out := csv.NewWriter(w)
+	out.Comma = delimiter
+	out.Write([]string{"timestamp", "event", "activity", "duration"})
+	for _, entry := range entries {
+		out.Write([]string{
+			entry.Timestamp.Format(time.RFC3339),
+			entry.Event,
+			entry.Name,
+			strconv.FormatFloat(entry.Duration.Seconds(), 'f', 0, 64),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// writeSessionsCSV writes one row per session.
+func writeSessionsCSV(w io.Writer, sessions []Session, delimiter rune) error {
+	out := csv.NewWriter(w)
+	out.Comma = delimiter
+	out.Write([]string{"activity", "start", "end", "duration"})
+	for _, session := range sessions {
+		out.Write([]string{
+			session.Name,
+			session.Start.Format(time.RFC3339),
+			session.End.Format(time.RFC3339),
+			strconv.FormatFloat(session.Duration.Seconds(), 'f', 0, 64),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// readCSV reads the header and records of a CSV file. A UTF-8 byte order
+// mark is skipped.
+func readCSV(r io.Reader, delimiter rune) ([]string, [][]string, error) {
+	in := csv.NewReader(r)
+	in.Comma = delimiter
+	in.FieldsPerRecord = -1
+	records, err := in.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("empty CSV file")
+	}
+
+	header := records[0]
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	return header, records[1:], nil
+}
+
+// guessCSVMapping maps fields to header columns with a known name.
+func guessCSVMapping(header []string) map[string]string {
+	mapping := map[string]string{}
+	for _, field := range csvFields {
+		for _, column := range header {
+			for _, alias := range csvAliases[field] {
+				if strings.EqualFold(strings.TrimSpace(column), alias) {
+					mapping[field] = column
+				}
+			}
+			if mapping[field] != "" {
+				break
+			}
+		}
+	}
+	return mapping
+}
+
+// parseMapping reads a mapping given as field=column pairs separated by
+// commas, on top of the guessed one.
+func parseMapping(text string, header []string) (map[string]string, error) {
+	mapping := guessCSVMapping(header)
+	for _, pair := range strings.Split(text, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		field, column, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid mapping %q, want field=column", pair)
+		}
+		mapping[strings.TrimSpace(field)] = strings.TrimSpace(column)
+	}
+	return mapping, nil
+}
+
+// parseTime accepts RFC 3339 and the common date-time layouts. Times
+// without a zone are local.
+func parseTime(text string) (time.Time, error) {
+	text = strings.TrimSpace(text)
+	if t, err := time.Parse(time.RFC3339, text); err == nil {
+		return t.Local(), nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, text, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", text)
+}
+
+// parseFlexibleDuration accepts seconds, hh:mm:ss, Go durations such as
+// 1h30m and the "12.5 minutes" written to the workbook.
+func parseFlexibleDuration(text string) (time.Duration, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, nil
+	}
+	if seconds, err := strconv.ParseFloat(text, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	if strings.HasSuffix(text, " minutes") {
+		return parseDuration(text), nil
+	}
+	if parts := strings.Split(text, ":"); len(parts) == 3 {
+		h, err1 := strconv.Atoi(parts[0])
+		m, err2 := strconv.Atoi(parts[1])
+		s, err3 := strconv.Atoi(parts[2])
+		if err1 == nil && err2 == nil && err3 == nil {
+			return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(s)*time.Second, nil
+		}
+	}
+	return time.ParseDuration(text)
+}
+
+// csvEntries converts CSV records to timer entries using mapping.
+func csvEntries(header []string, records [][]string, mapping map[string]string) ([]TimerEntry, error) {
+	index := map[string]int{}
+	for field, column := range mapping {
+		if column == "" {
+			continue
+		}
+		found := false
+		for i, name := range header {
+			if name == column {
+				index[field] = i
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no column %q for %s", column, field)
+		}
+	}
+
+	_, hasStart := index["start"]
+	_, hasEnd := index["end"]
+	_, hasTimestamp := index["timestamp"]
+	_, hasEvent := index["event"]
+	sessions := hasStart && hasEnd
+	if !sessions && !(hasTimestamp && hasEvent) {
+		return nil, fmt.Errorf("map either timestamp and event, or start and end")
+	}
+
+	value := func(record []string, field string) string {
+		i, ok := index[field]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return record[i]
+	}
+
+	var entries []TimerEntry
+	for n, record := range records {
+		line := n + 2
+		duration, err := parseFlexibleDuration(value(record, "duration"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if sessions {
+			start, err := parseTime(value(record, "start"))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			end, err := parseTime(value(record, "end"))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			entries = append(entries, sessionEntries(Session{
+				Name:     value(record, "activity"),
+				Start:    start,
+				End:      end,
+				Duration: duration,
+			})...)
+			continue
+		}
+
+		timestamp, err := parseTime(value(record, "timestamp"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		entries = append(entries, TimerEntry{
+			Timestamp: timestamp,
+			Event:     strings.ToUpper(strings.TrimSpace(value(record, "event"))),
+			Name:      value(record, "activity"),
+			Duration:  duration,
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+var (
+	// DataDir overrides the per-user data directory when set.
+	DataDir string
+
+	flagDataDir string
+	flagFile    string
+)
+
+// defaultDataDir returns the per-user data directory:
+// $XDG_DATA_HOME/timer or ~/.local/share/timer on Linux.
+func defaultDataDir() string {
+	base := os.Getenv("XDG_DATA_HOME")
+	if base == "" {
+		switch runtime.GOOS {
+		case "windows":
+			base = os.Getenv("LOCALAPPDATA")
+		case "darwin":
+			if home, err := os.UserHomeDir(); err == nil {
+				base = filepath.Join(home, "Library", "Application Support")
+			}
+		default:
+			if home, err := os.UserHomeDir(); err == nil {
+				base = filepath.Join(home, ".local", "share")
+			}
+		}
+	}
+	if base == "" {
+		base = os.TempDir()
+	}
+	return filepath.Join(base, "timer")
+}
+
+// dataDir returns the directory data files are kept in. The -data-dir
+// flag wins over the config file, which wins over the default.
+func dataDir() string {
+	if flagDataDir != "" {
+		return flagDataDir
+	}
+	if DataDir != "" {
+		return DataDir
+	}
+	return defaultDataDir()
+}
+
+// dataFilePath returns where the workbook is stored. A relative file
+// name is taken to be inside dataDir.
+func dataFilePath() string {
+	name := excelFileName
+	if flagFile != "" {
+		name = flagFile
+	}
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(dataDir(), name)
+}
+
+// initDataDir creates the data directory and moves a workbook left in
+// the working directory by older versions into it, once.
+func initDataDir() error {
+	path := dataFilePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	old, err := filepath.Abs(filepath.Base(excelFileName))
+	if err != nil || old == path {
+		return nil
+	}
+	if _, err := os.Stat(old); err != nil {
+		return nil
+	}
+
+	if err := copyFile(old, path); err != nil {
+		return fmt.Errorf("migrating %s: %w", old, err)
+	}
+	if err := os.Rename(old, old+".migrated"); err != nil {
+		return fmt.Errorf("migrated %s but could not rename it: %w", old, err)
+	}
+	return nil
+}
+
+func copyFile(from string, to string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(to)
+		return err
+	}
+	return dst.Close()
+}
@@ -1,39 +1,74 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/widget"
 	"github.com/xuri/excelize/v2"
 )
 
+// storageMu serialises access to the workbook, so a write is never read
+// or overwritten half way.
+var storageMu sync.Mutex
+
 func initExcelFile() {
+	storageMu.Lock()
+	defer storageMu.Unlock()
+
 	date := time.Now().Format("2006-01-02")
 	sheetName := date
 
-	if _, err := os.Stat(excelFileName); os.IsNotExist(err) {
+	if _, err := os.Stat(dataFilePath()); os.IsNotExist(err) {
 		f := excelize.NewFile()
-		f.NewSheet(sheetName)
-		f.SetCellValue(sheetName, "A1", "Timestamp")
-		f.SetCellValue(sheetName, "B1", "Event")
-		f.SetCellValue(sheetName, "C1", "Activity Name")
-		f.SetCellValue(sheetName, "D1", "Duration")
+		newDaySheet(f, sheetName)
 		f.DeleteSheet("Sheet1")
-		if err := f.SaveAs(excelFileName); err != nil {
-			t := fmt.Sprint("Error creating Excel file:", err)
-			LogEntry.SetText(t)
+		if err := f.SaveAs(dataFilePath()); err != nil {
+			slog.Error("Error creating Excel file", "err", err)
 		}
 	}
 }
 
+// newDaySheet adds a day sheet with its header row.
+func newDaySheet(f *excelize.File, sheetName string) {
+	f.NewSheet(sheetName)
+	f.SetCellValue(sheetName, "A1", "Timestamp")
+	f.SetCellValue(sheetName, "B1", "Event")
+	f.SetCellValue(sheetName, "C1", "Activity Name")
+	f.SetCellValue(sheetName, "D1", "Duration")
+	f.SetCellValue(sheetName, "E1", "Notes")
+}
+
+// setNotes writes an entry's notes to column E, adding the header to
+// day sheets from before notes were kept.
+func setNotes(f *excelize.File, sheetName string, rowIndex int, notes string) {
+	if notes == "" {
+		return
+	}
+	if header, _ := f.GetCellValue(sheetName, "E1"); header == "" {
+		f.SetCellValue(sheetName, "E1", "Notes")
+	}
+	f.SetCellValue(sheetName, fmt.Sprintf("E%d", rowIndex), notes)
+}
+
 func saveToExcel(entry TimerEntry) {
-	f, err := excelize.OpenFile(excelFileName)
+	storageMu.Lock()
+	defer storageMu.Unlock()
+
+	f, err := excelize.OpenFile(dataFilePath())
 	if err != nil {
-		fmt.Println("Error opening Excel file:", err)
+		slog.Error("Error opening Excel file", "err", err)
+		storageWriteErrors.Add(1)
 		return
 	}
 	defer f.Close()
@@ -43,20 +78,15 @@ func saveToExcel(entry TimerEntry) {
 
 	// Create a new sheet for a new day if it doesn't exist
 	if sheetIndex, err := f.GetSheetIndex(sheetName); err != nil || sheetIndex == -1 {
-		f.NewSheet(sheetName)
-		f.SetCellValue(sheetName, "A1", "Timestamp")
-		f.SetCellValue(sheetName, "B1", "Event")
-		f.SetCellValue(sheetName, "C1", "Activity Name")
-		f.SetCellValue(sheetName, "D1", "Duration")
+		newDaySheet(f, sheetName)
 		f.DeleteSheet("Sheet1")
 	}
 
 	// Get the last row index
 	rows, err := f.GetRows(sheetName)
 	if err != nil {
-		t := fmt.Sprint("Error getting rows:", err)
-		LogEntry.SetText(t)
-
+		slog.Error("Error getting rows", "err", err)
+		storageWriteErrors.Add(1)
 		return
 	}
 	rowIndex := len(rows) + 1
@@ -70,16 +100,62 @@ func saveToExcel(entry TimerEntry) {
 		f.SetCellValue(sheetName, fmt.Sprintf("D%d", rowIndex),
 			fmt.Sprintf("%.1f minutes", entry.Duration.Minutes()))
 	}
+	setNotes(f, sheetName, rowIndex, entry.Notes)
 
 	// Save the file
 	if err := f.Save(); err != nil {
-		t := fmt.Sprint("Error saving Excel file:", err)
 		handleExcelInUse(err, f)
-		LogEntry.SetText(t)
+		slog.Error("Error saving Excel file", "err", err)
+		storageWriteErrors.Add(1)
 	} else {
-		LogEntry.SetText("Data saved successfully.")
-		resetLogText()
+		recordMetrics(entry)
+		slog.Info("Data saved successfully.")
+	}
+}
+
+// removeFromExcel deletes the row on the entry's day sheet with its
+// event, activity name and timestamp, to the second.
+func removeFromExcel(entry TimerEntry) error {
+	storageMu.Lock()
+	defer storageMu.Unlock()
+
+	f, err := excelize.OpenFile(dataFilePath())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sheetName := entry.Timestamp.Format("2006-01-02")
+	rows, err := f.GetRows(sheetName, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return err
+	}
+
+	for i := len(rows) - 1; i > 0; i-- {
+		row := rows[i]
+		name := ""
+		if len(row) > 2 {
+			name = row[2]
+		}
+		if len(row) < 2 || row[1] != entry.Event || name != entry.Name {
+			continue
+		}
+		if t, ok := cellTime(row[0]); !ok || t.Sub(entry.Timestamp).Abs() >= time.Second {
+			continue
+		}
+
+		if err := f.RemoveRow(sheetName, i+1); err != nil {
+			return err
+		}
+		if err := f.Save(); err != nil {
+			handleExcelInUse(err, f)
+			return err
+		}
+
+		forgetMetrics(entry)
+		return nil
 	}
+	return fmt.Errorf("no %s row of %q at %s", entry.Event, entry.Name, entry.Timestamp.Format("2006-01-02 15:04:05"))
 }
 
 func choinceSwitch(choice int, f *excelize.File) {
@@ -87,30 +163,27 @@ func choinceSwitch(choice int, f *excelize.File) {
 	case 1:
 		uniqueFilename := getUniqueFilename()
 		if err := f.SaveAs(uniqueFilename); err != nil {
-			t := fmt.Sprint("Error saving Excel file:", err)
-			LogEntry.SetText(t)
+			slog.Error("Error saving Excel file", "err", err)
 		} else {
-			t := fmt.Sprint("Data saved to:", uniqueFilename)
-			LogEntry.SetText(t)
-			resetLogText()
+			slog.Info("Data saved to", "file", uniqueFilename)
 		}
 	case 2:
-		if err := saveWithRetry(f, excelFileName, 3); err != nil {
-			t := fmt.Sprint("Error saving Excel file:", err)
-			LogEntry.SetText(t)
+		if err := saveWithRetry(f, dataFilePath(), 3); err != nil {
+			slog.Error("Error saving Excel file", "err", err)
 		}
 	case 3:
-		LogEntry.SetText("Exiting...")
+		slog.Info("Exiting...")
 	default:
-		LogEntry.SetText("Invalid choice")
+		slog.Warn("Invalid choice")
 	}
 }
 
-func handleExcelInUse(err error, f *excelize.File) {
+// handleExcelInUse asks what to do with f, which could not be saved,
+// and returns the window asking.
+func handleExcelInUse(err error, f *excelize.File) fyne.Window {
 	if f == nil {
-		t := "handleExcelInUse(): Excel file is nil"
-		LogEntry.SetText(t)
-		return
+		slog.Error("handleExcelInUse(): Excel file is nil")
+		return nil
 	}
 
 	t := fmt.Sprint("Error saving Excel file:", err)
@@ -124,8 +197,7 @@ func handleExcelInUse(err error, f *excelize.File) {
 
 	submitButton := button("Submit", func() {
 		if inputField == nil {
-			t := "handleExcelInUse(): inputField is nil"
-			LogEntry.SetText(t)
+			slog.Error("handleExcelInUse(): inputField is nil")
 			return
 		}
 
@@ -133,15 +205,13 @@ func handleExcelInUse(err error, f *excelize.File) {
 		var err error
 		choice, err = strconv.Atoi(choiceText)
 		if err != nil {
-			t := fmt.Sprint("Invalid choice:", err)
-			LogEntry.SetText(t)
+			slog.Warn("Invalid choice", "err", err)
 			return
 		}
 
 		choinceSwitch(choice, f)
 		if w == nil {
-			t := "handleExcelInUse(): w is nil"
-			LogEntry.SetText(t)
+			slog.Error("handleExcelInUse(): w is nil")
 			return
 		}
 
@@ -149,19 +219,19 @@ func handleExcelInUse(err error, f *excelize.File) {
 	})
 
 	if w == nil {
-		t := "handleExcelInUse(): w is nil"
-		LogEntry.SetText(t)
-		return
+		slog.Error("handleExcelInUse(): w is nil")
+		return nil
 	}
 
 	w.SetContent(container.NewVBox(inputField, submitButton))
 	w.Show()
 
-	fmt.Print("Enter your choice: ")
+	slog.Debug("Waiting for a choice")
+	return w
 }
 
 func getUniqueFilename() string {
-	return fmt.Sprintf("report_%s.xlsx", time.Now().Format("20060102_150405"))
+	return filepath.Join(dataDir(), fmt.Sprintf("report_%s.xlsx", time.Now().Format("20060102_150405")))
 }
 
 func saveWithRetry(f *excelize.File, filename string, maxRetries int) error {
@@ -175,11 +245,450 @@ func saveWithRetry(f *excelize.File, filename string, maxRetries int) error {
 }
 
 func resetLogText() {
-	Wg.Add(1)
 	go func() {
-		defer Wg.Done()
-		time.Sleep(6 * time.Second) // Let saveToExcel finish
+		time.Sleep(LogResetDelay) // Let saveToExcel finish
 
 		LogEntry.SetText("logs:...")
 	}()
 }
+
+const summarySheet = "Summary"
+
+// loadPomodoroCount reads the number of completed pomodoros for date
+// from the summary sheet.
+func loadPomodoroCount(date string) int {
+	storageMu.Lock()
+	defer storageMu.Unlock()
+
+	f, err := excelize.OpenFile(dataFilePath())
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	rows, err := f.GetRows(summarySheet)
+	if err != nil {
+		return 0
+	}
+	for i, row := range rows {
+		if i > 0 && len(row) > 1 && row[0] == date {
+			count, _ := strconv.Atoi(row[1])
+			return count
+		}
+	}
+	return 0
+}
+
+// savePomodoroCount writes the number of completed pomodoros for date
+// to the summary sheet, adding a row for a new day.
+func savePomodoroCount(date string, count int) {
+	storageMu.Lock()
+	defer storageMu.Unlock()
+
+	f, err := excelize.OpenFile(dataFilePath())
+	if err != nil {
+		slog.Error("Error opening Excel file", "err", err)
+		return
+	}
+	defer f.Close()
+
+	if sheetIndex, err := f.GetSheetIndex(summarySheet); err != nil || sheetIndex == -1 {
+		f.NewSheet(summarySheet)
+		f.SetCellValue(summarySheet, "A1", "Date")
+		f.SetCellValue(summarySheet, "B1", "Pomodoros")
+	}
+
+	rows, err := f.GetRows(summarySheet)
+	if err != nil {
+		slog.Error("Error getting rows", "err", err)
+		return
+	}
+
+	rowIndex := len(rows) + 1
+	for i, row := range rows {
+		if i > 0 && len(row) > 0 && row[0] == date {
+			rowIndex = i + 1
+			break
+		}
+	}
+	f.SetCellValue(summarySheet, fmt.Sprintf("A%d", rowIndex), date)
+	f.SetCellValue(summarySheet, fmt.Sprintf("B%d", rowIndex), count)
+
+	if err := f.Save(); err != nil {
+		handleExcelInUse(err, f)
+		slog.Error("Error saving Excel file", "err", err)
+	}
+}
+
+// countsTowardsTotal reports whether the duration of an event is time
+// spent on its activity. PAUSE and pomodoro rows carry no or partial
+// durations that are already part of the STOP row.
+func countsTowardsTotal(event string) bool {
+	switch event {
+	case "STOP", "EXIT", "REASSIGN":
+		return true
+	}
+	return false
+}
+
+// parseDuration reads a Duration cell written by saveToExcel.
+func parseDuration(cell string) time.Duration {
+	minutes, err := strconv.ParseFloat(strings.TrimSuffix(cell, " minutes"), 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(minutes * float64(time.Minute))
+}
+
+// readEntries returns the rows of every day sheet from the date of from
+// to the date of to, inclusive. Days without a sheet are skipped.
+func readEntries(from, to time.Time) ([]TimerEntry, error) {
+	storageMu.Lock()
+	defer storageMu.Unlock()
+
+	f, err := excelize.OpenFile(dataFilePath())
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []TimerEntry
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.Local)
+	for ; !day.After(to); day = day.AddDate(0, 0, 1) {
+		sheetName := day.Format("2006-01-02")
+		if sheetIndex, err := f.GetSheetIndex(sheetName); err != nil || sheetIndex == -1 {
+			continue
+		}
+
+		dayEntries, err := readSheet(f, sheetName)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, dayEntries...)
+	}
+	return entries, nil
+}
+
+// readSheet parses the rows of one day sheet.
+// cellTime reads the raw value of a timestamp cell as local time,
+// rounded to the second.
+func cellTime(value string) (time.Time, bool) {
+	serial, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	t, err := excelize.ExcelDateToTime(serial, false)
+	if err != nil {
+		return time.Time{}, false
+	}
+	t = t.Round(time.Second)
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.Local), true
+}
+
+func readSheet(f *excelize.File, sheetName string) ([]TimerEntry, error) {
+	rows, err := f.GetRows(sheetName, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []TimerEntry
+	for i, row := range rows {
+		if i == 0 || len(row) < 2 {
+			continue
+		}
+
+		entry := TimerEntry{Event: row[1]}
+		entry.Timestamp, _ = cellTime(row[0])
+		if len(row) > 2 {
+			entry.Name = row[2]
+		}
+		if len(row) > 3 {
+			entry.Duration = parseDuration(row[3])
+		}
+		if len(row) > 4 {
+			entry.Notes = row[4]
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// sheetDays returns the dates that have a day sheet, oldest first.
+func sheetDays() ([]time.Time, error) {
+	storageMu.Lock()
+	defer storageMu.Unlock()
+
+	f, err := excelize.OpenFile(dataFilePath())
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var days []time.Time
+	for _, name := range f.GetSheetList() {
+		if day, err := time.ParseInLocation("2006-01-02", name, time.Local); err == nil {
+			days = append(days, day)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days, nil
+}
+
+// sameEntry reports whether two entries record the same event, to the
+// second.
+func sameEntry(a, b TimerEntry) bool {
+	return a.Timestamp.Truncate(time.Second).Equal(b.Timestamp.Truncate(time.Second)) &&
+		a.Event == b.Event && a.Name == b.Name
+}
+
+// appendEntries writes entries to their day sheets in one go, skipping
+// any that are already there. It returns how many were added and how
+// many were skipped as duplicates.
+func appendEntries(entries []TimerEntry) (int, int, error) {
+	storageMu.Lock()
+	defer storageMu.Unlock()
+
+	f, err := excelize.OpenFile(dataFilePath())
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	added, skipped := 0, 0
+	var written []TimerEntry
+	existing := map[string][]TimerEntry{}
+	nextRow := map[string]int{}
+	for _, entry := range entries {
+		sheetName := entry.Timestamp.Format("2006-01-02")
+
+		if _, ok := nextRow[sheetName]; !ok {
+			if sheetIndex, err := f.GetSheetIndex(sheetName); err != nil || sheetIndex == -1 {
+				newDaySheet(f, sheetName)
+			}
+			rows, err := f.GetRows(sheetName)
+			if err != nil {
+				return 0, 0, err
+			}
+			if existing[sheetName], err = readSheet(f, sheetName); err != nil {
+				return 0, 0, err
+			}
+			nextRow[sheetName] = len(rows) + 1
+		}
+
+		duplicate := false
+		for _, other := range existing[sheetName] {
+			if sameEntry(entry, other) {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			skipped++
+			continue
+		}
+
+		rowIndex := nextRow[sheetName]
+		f.SetCellValue(sheetName, fmt.Sprintf("A%d", rowIndex), entry.Timestamp)
+		f.SetCellValue(sheetName, fmt.Sprintf("B%d", rowIndex), entry.Event)
+		f.SetCellValue(sheetName, fmt.Sprintf("C%d", rowIndex), entry.Name)
+		if entry.Duration > 0 {
+			f.SetCellValue(sheetName, fmt.Sprintf("D%d", rowIndex),
+				fmt.Sprintf("%.1f minutes", entry.Duration.Minutes()))
+		}
+		setNotes(f, sheetName, rowIndex, entry.Notes)
+		nextRow[sheetName]++
+		existing[sheetName] = append(existing[sheetName], entry)
+		written = append(written, entry)
+		added++
+	}
+
+	if added > 0 {
+		if err := f.Save(); err != nil {
+			return 0, 0, err
+		}
+	}
+	for _, entry := range written {
+		recordMetrics(entry)
+	}
+	return added, skipped, nil
+}
+
+// trackedTime sums the finished time recorded for an activity between
+// the dates of from and to.
+func trackedTime(name string, from, to time.Time) time.Duration {
+	entries, err := readEntries(from, to)
+	if err != nil {
+		return 0
+	}
+
+	var total time.Duration
+	for _, entry := range entries {
+		if entry.Name == name && countsTowardsTotal(entry.Event) {
+			total += entry.Duration
+		}
+	}
+	return total
+}
+
+const activitiesSheet = "Activities"
+
+// loadActivity looks up an activity definition by name.
+func loadActivity(name string) (Activity, bool) {
+	activity, ok := loadActivities()[name]
+	return activity, ok
+}
+
+// loadActivities returns every activity definition by name.
+func loadActivities() map[string]Activity {
+	storageMu.Lock()
+	defer storageMu.Unlock()
+
+	activities := map[string]Activity{}
+	f, err := excelize.OpenFile(dataFilePath())
+	if err != nil {
+		return activities
+	}
+	defer f.Close()
+
+	rows, err := f.GetRows(activitiesSheet)
+	if err != nil {
+		return activities
+	}
+	for i, row := range rows {
+		if i == 0 || len(row) == 0 {
+			continue
+		}
+
+		activity := Activity{Name: row[0]}
+		if len(row) > 1 {
+			activity.DailyBudget = parseDuration(row[1])
+		}
+		if len(row) > 2 {
+			activity.WeeklyBudget = parseDuration(row[2])
+		}
+		if len(row) > 3 {
+			activity.Project = row[3]
+		}
+		if len(row) > 4 {
+			activity.Client = row[4]
+		}
+		activities[activity.Name] = activity
+	}
+	return activities
+}
+
+// excelSaveError is a change to the workbook that could not be saved.
+// reopen opens the workbook again with the change applied, so the GUI
+// can offer to save it elsewhere or retry.
+type excelSaveError struct {
+	err    error
+	reopen func() (*excelize.File, error)
+}
+
+func (e *excelSaveError) Error() string { return "saving workbook: " + e.err.Error() }
+func (e *excelSaveError) Unwrap() error { return e.err }
+
+// showSaveError logs err and, when it is a failed save, asks the user
+// what to do with the unsaved change. Only GUI callers use it.
+func showSaveError(message string, err error) {
+	slog.Error(message, "err", err)
+	var saveErr *excelSaveError
+	if !errors.As(err, &saveErr) {
+		return
+	}
+
+	f, err := saveErr.reopen()
+	if err != nil {
+		slog.Error("Error reopening Excel file", "err", err)
+		return
+	}
+	if w := handleExcelInUse(saveErr.err, f); w != nil {
+		w.SetOnClosed(func() { f.Close() })
+	} else {
+		f.Close()
+	}
+}
+
+// saveActivity writes an activity definition, replacing an existing one
+// with the same name.
+func saveActivity(activity Activity) error {
+	return saveActivities([]Activity{activity})
+}
+
+// saveActivities writes activity definitions in one save, replacing
+// existing ones with the same names.
+func saveActivities(activities []Activity) error {
+	if len(activities) == 0 {
+		return nil
+	}
+
+	storageMu.Lock()
+	defer storageMu.Unlock()
+
+	f, err := openWithActivities(activities)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := f.Save(); err != nil {
+		return &excelSaveError{err: err, reopen: func() (*excelize.File, error) {
+			storageMu.Lock()
+			defer storageMu.Unlock()
+			return openWithActivities(activities)
+		}}
+	}
+	return nil
+}
+
+// openWithActivities opens the workbook and writes activity definitions
+// into it without saving. The caller holds storageMu and closes the file.
+func openWithActivities(activities []Activity) (*excelize.File, error) {
+	f, err := excelize.OpenFile(dataFilePath())
+	if err != nil {
+		return nil, err
+	}
+
+	if sheetIndex, err := f.GetSheetIndex(activitiesSheet); err != nil || sheetIndex == -1 {
+		f.NewSheet(activitiesSheet)
+		f.SetCellValue(activitiesSheet, "A1", "Activity Name")
+		f.SetCellValue(activitiesSheet, "B1", "Daily Budget")
+		f.SetCellValue(activitiesSheet, "C1", "Weekly Budget")
+	}
+	if header, _ := f.GetCellValue(activitiesSheet, "D1"); header == "" {
+		f.SetCellValue(activitiesSheet, "D1", "Project")
+	}
+	if header, _ := f.GetCellValue(activitiesSheet, "E1"); header == "" {
+		f.SetCellValue(activitiesSheet, "E1", "Client")
+	}
+
+	rows, err := f.GetRows(activitiesSheet)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	rowOf := map[string]int{}
+	for i, row := range rows {
+		if i > 0 && len(row) > 0 {
+			rowOf[row[0]] = i + 1
+		}
+	}
+
+	nextRow := len(rows) + 1
+	for _, activity := range activities {
+		rowIndex, ok := rowOf[activity.Name]
+		if !ok {
+			rowIndex = nextRow
+			rowOf[activity.Name] = rowIndex
+			nextRow++
+		}
+		f.SetCellValue(activitiesSheet, fmt.Sprintf("A%d", rowIndex), activity.Name)
+		f.SetCellValue(activitiesSheet, fmt.Sprintf("B%d", rowIndex),
+			fmt.Sprintf("%.1f minutes", activity.DailyBudget.Minutes()))
+		f.SetCellValue(activitiesSheet, fmt.Sprintf("C%d", rowIndex),
+			fmt.Sprintf("%.1f minutes", activity.WeeklyBudget.Minutes()))
+		f.SetCellValue(activitiesSheet, fmt.Sprintf("D%d", rowIndex), activity.Project)
+		f.SetCellValue(activitiesSheet, fmt.Sprintf("E%d", rowIndex), activity.Client)
+	}
+	return f, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAppendEntriesSkipsDuplicates(t *testing.T) {
+	old := flagDataDir
+	flagDataDir = t.TempDir()
+	t.Cleanup(func() { flagDataDir = old })
+	initExcelFile()
+
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.Local)
+	entries := sessionEntries(Session{Name: "Code", Start: start, End: start.Add(time.Hour), Notes: "Parser"})
+
+	added, skipped, err := appendEntries(entries)
+	if err != nil || added != 2 || skipped != 0 {
+		t.Fatalf("first import: added %d, skipped %d, err %v", added, skipped, err)
+	}
+	added, skipped, err = appendEntries(append(entries, TimerEntry{Timestamp: start.Add(2 * time.Hour), Event: "START", Name: "Docs"}))
+	if err != nil || added != 1 || skipped != 2 {
+		t.Fatalf("second import: added %d, skipped %d, err %v, want 1 and 2", added, skipped, err)
+	}
+
+	sessions, err := readSessions(start, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].Duration != time.Hour || sessions[0].Notes != "Parser" {
+		t.Errorf("sessions read back = %+v", sessions)
+	}
+}
+
+func TestRemoveFromExcelMatchesTimestamp(t *testing.T) {
+	old := flagDataDir
+	flagDataDir = t.TempDir()
+	t.Cleanup(func() { flagDataDir = old })
+	initExcelFile()
+
+	at := func(hour int) time.Time {
+		return time.Date(2024, 3, 4, hour, 0, 0, 0, time.Local)
+	}
+	first := TimerEntry{Timestamp: at(9), Event: "STOP", Name: "Code", Duration: time.Hour}
+	second := TimerEntry{Timestamp: at(11), Event: "STOP", Name: "Code", Duration: time.Hour}
+	saveToExcel(first)
+	saveToExcel(second)
+
+	if err := removeFromExcel(first); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := readEntries(at(0), at(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || !entries[0].Timestamp.Equal(second.Timestamp) {
+		t.Errorf("rows left = %+v, want only the later STOP", entries)
+	}
+
+	if err := removeFromExcel(first); err == nil {
+		t.Error("removing a row that is gone did not fail")
+	}
+}
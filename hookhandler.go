@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Hook is a command run when the timer records an event. Event is one
+// of START, PAUSE, RESUME, STOP or EXIT, or "*" for all of them.
+type Hook struct {
+	Event   string        `toml:"event"`
+	Command string        `toml:"command"`
+	Timeout time.Duration `toml:"timeout"`
+}
+
+var (
+	Hooks       []Hook
+	HookTimeout = 30 * time.Second
+
+	hookWg  sync.WaitGroup
+	hookLog sync.Mutex
+)
+
+// hookPayload is the JSON a hook reads on stdin. Its shape is a contract
+// with users' scripts: keep it as it is, the duration in float seconds.
+type hookPayload struct {
+	Event     string  `json:"event"`
+	Name      string  `json:"name"`
+	Timestamp string  `json:"timestamp"`
+	Duration  float64 `json:"duration"`
+}
+
+func newHookPayload(entry TimerEntry) hookPayload {
+	return hookPayload{
+		Event:     entry.Event,
+		Name:      entry.Name,
+		Timestamp: entry.Timestamp.Format(time.RFC3339),
+		Duration:  entry.Duration.Seconds(),
+	}
+}
+
+// runHooks starts every hook for the entry's event in the background.
+func runHooks(entry TimerEntry) {
+	for _, hook := range Hooks {
+		if hook.Event != "*" && hook.Event != entry.Event {
+			continue
+		}
+
+		hookWg.Add(1)
+		go func() {
+			defer hookWg.Done()
+			runHook(hook, entry)
+		}()
+	}
+}
+
+// waitHooks waits until deadline for running hooks and reports whether
+// they all finished.
+func waitHooks(deadline time.Time) bool {
+	finished := make(chan struct{})
+	go func() {
+		hookWg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return true
+	case <-time.After(time.Until(deadline)):
+		return false
+	}
+}
+
+// runHook runs one hook through the shell. The entry is passed as
+// TIMER_* environment variables and as JSON on stdin, and the output
+// is appended to hooks.log in the data directory.
+func runHook(hook Hook, entry TimerEntry) {
+	timeout := hook.Timeout
+	if timeout <= 0 {
+		timeout = HookTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd.exe", "/C", hook.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", hook.Command)
+	}
+	setProcessGroup(cmd)
+	// Children left holding the output open must not keep us waiting.
+	cmd.WaitDelay = time.Second
+
+	payload, _ := json.Marshal(newHookPayload(entry))
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Env = append(os.Environ(),
+		"TIMER_EVENT="+entry.Event,
+		"TIMER_NAME="+entry.Name,
+		"TIMER_TIMESTAMP="+entry.Timestamp.Format(time.RFC3339),
+		"TIMER_DURATION="+strconv.FormatFloat(entry.Duration.Seconds(), 'f', 0, 64),
+	)
+
+	started := time.Now()
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	writeHookLog(hook, entry, started, output, err)
+}
+
+func writeHookLog(hook Hook, entry TimerEntry, started time.Time, output []byte, err error) {
+	hookLog.Lock()
+	defer hookLog.Unlock()
+
+	file, openErr := os.OpenFile(filepath.Join(dataDir(), "hooks.log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if openErr != nil {
+		slog.Error("Error opening hook log", "err", openErr)
+		return
+	}
+	defer file.Close()
+
+	status := "ok"
+	if err != nil {
+		status = err.Error()
+		slog.Warn("Hook failed", "event", entry.Event, "err", err)
+	}
+	fmt.Fprintf(file, "%s %s %q (%s): %s\n", started.Format(time.RFC3339), entry.Event, hook.Command,
+		time.Since(started).Round(time.Millisecond), status)
+	if len(output) > 0 {
+		file.Write(output)
+		if output[len(output)-1] != '\n' {
+			file.WriteString("\n")
+		}
+	}
+}
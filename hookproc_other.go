@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+import "os/exec"
+
+// setProcessGroup leaves cmd as it is; killing the shell on timeout is
+// all that is done without process groups.
+func setProcessGroup(cmd *exec.Cmd) {}
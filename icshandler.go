@@ -0,0 +1,608 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const icsTimeLayout = "20060102T150405Z"
+
+// icsEscape escapes a TEXT value as RFC 5545 requires.
+func icsEscape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(text)
+}
+
+// writeICSLine writes a content line, folded so no line is longer than
+// 75 octets.
+func writeICSLine(w *bufio.Writer, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// Continuation lines start with the folding space.
+		limit = 74
+	}
+	w.WriteString(line + "\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// sessionUID gives a session the same UID on every export, so calendars
+// update events instead of duplicating them.
+func sessionUID(session Session) string {
+	h := fnv.New64a()
+	h.Write([]byte(session.Name))
+	return fmt.Sprintf("%d-%x@timer", session.Start.Unix(), h.Sum64())
+}
+
+// writeSessionsICS writes one VEVENT per session. The description holds
+// the session's notes, then the tracked time and the tags.
+func writeSessionsICS(w io.Writer, sessions []Session) error {
+	out := bufio.NewWriter(w)
+	stamp := time.Now().UTC().Format(icsTimeLayout)
+
+	writeICSLine(out, "BEGIN:VCALENDAR")
+	writeICSLine(out, "VERSION:2.0")
+	writeICSLine(out, "PRODID:-//timer//time tracker//EN")
+	writeICSLine(out, "CALSCALE:GREGORIAN")
+	for _, session := range sessions {
+		description := "Tracked " + formatDuration(session.Duration)
+		if session.Notes != "" {
+			description = session.Notes + "\n\n" + description
+		}
+		if tags := activityTags(session.Name); len(tags) > 0 {
+			description += "\nTags: " + strings.Join(tags, ", ")
+		}
+
+		writeICSLine(out, "BEGIN:VEVENT")
+		writeICSLine(out, "UID:"+sessionUID(session))
+		writeICSLine(out, "DTSTAMP:"+stamp)
+		writeICSLine(out, "DTSTART:"+session.Start.UTC().Format(icsTimeLayout))
+		writeICSLine(out, "DTEND:"+session.End.UTC().Format(icsTimeLayout))
+		writeICSLine(out, "SUMMARY:"+icsEscape(session.Name))
+		writeICSLine(out, "DESCRIPTION:"+icsEscape(description))
+		writeICSLine(out, "TRANSP:TRANSPARENT")
+		writeICSLine(out, "END:VEVENT")
+	}
+	writeICSLine(out, "END:VCALENDAR")
+	return out.Flush()
+}
+
+// exportICS writes the sessions of query as an iCalendar file.
+func exportICS(w io.Writer, query sessionQuery) error {
+	sessions, err := filteredSessions(query)
+	if err != nil {
+		return err
+	}
+	return writeSessionsICS(w, sessions)
+}
+
+func exportICSCommand(args []string) error {
+	flags := flag.NewFlagSet("export-ics", flag.ContinueOnError)
+	shared := sessionFilterFlags(flags, "")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	query, out, err := shared.open()
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return exportICS(out, query)
+}
+
+// icsEvent is a VEVENT read from an iCalendar file.
+type icsEvent struct {
+	Summary   string
+	Start     time.Time
+	End       time.Time
+	AllDay    bool
+	Cancelled bool
+
+	// Rule is the RRULE of a recurring event, and RDates and ExDates the
+	// occurrences added to and taken out of it.
+	Rule    string
+	RDates  []time.Time
+	ExDates []time.Time
+
+	// An event with a RecurrenceID replaces that occurrence of the
+	// recurring event with the same UID.
+	UID          string
+	RecurrenceID time.Time
+
+	// zone is the time zone of DTSTART, which occurrences keep their
+	// wall clock time in.
+	zone *time.Location
+}
+
+// recurring reports whether the event has more than one occurrence.
+func (e icsEvent) recurring() bool {
+	return e.Rule != "" || len(e.RDates) > 0
+}
+
+// icsUnescape reverses icsEscape.
+func icsUnescape(text string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(text)
+}
+
+// parseICSTime reads a DATE-TIME or DATE value in its own time zone.
+// params holds the property parameters, such as TZID=Europe/Berlin.
+// Times without a zone are local.
+func parseICSTime(value string, params []string) (time.Time, bool, error) {
+	location := time.Local
+	for _, param := range params {
+		if name, tzid, ok := strings.Cut(param, "="); ok && strings.EqualFold(name, "TZID") {
+			if l, err := time.LoadLocation(strings.Trim(tzid, `"`)); err == nil {
+				location = l
+			}
+		}
+	}
+
+	if len(value) == 8 {
+		t, err := time.ParseInLocation("20060102", value, time.Local)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(icsTimeLayout, value)
+		return t, false, err
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, location)
+	return t, false, err
+}
+
+// parseICSTimes reads the comma-separated values of RDATE and EXDATE. A
+// PERIOD value counts as its start.
+func parseICSTimes(value string, params []string) ([]time.Time, error) {
+	var times []time.Time
+	for _, v := range strings.Split(value, ",") {
+		v, _, _ = strings.Cut(v, "/")
+		t, _, err := parseICSTime(v, params)
+		if err != nil {
+			return nil, err
+		}
+		times = append(times, t)
+	}
+	return times, nil
+}
+
+// parseICSDuration reads a duration such as PT1H30M or P1D.
+func parseICSDuration(value string) (time.Duration, error) {
+	text := strings.TrimPrefix(strings.TrimPrefix(value, "+"), "P")
+	var total time.Duration
+	number := 0
+	inTime := false
+	units := map[bool]map[byte]time.Duration{
+		false: {'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour},
+		true:  {'H': time.Hour, 'M': time.Minute, 'S': time.Second},
+	}
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == 'T':
+			inTime = true
+		case c >= '0' && c <= '9':
+			number = number*10 + int(c-'0')
+		default:
+			unit, ok := units[inTime][c]
+			if !ok {
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+			total += time.Duration(number) * unit
+			number = 0
+		}
+	}
+	return total, nil
+}
+
+// parseICS reads the events of an iCalendar file.
+func parseICS(r io.Reader) ([]icsEvent, error) {
+	// Unfold continuation lines first.
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var events []icsEvent
+	var event *icsEvent
+	var duration time.Duration
+	depth := 0
+	for n, line := range lines {
+		nameParams, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		params := strings.Split(nameParams, ";")
+		name := strings.ToUpper(params[0])
+
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			event = &icsEvent{}
+			duration = 0
+			depth = 0
+		case event == nil:
+		case name == "BEGIN":
+			// Skip nested components such as VALARM.
+			depth++
+		case name == "END" && depth > 0:
+			depth--
+		case depth > 0:
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if event.End.IsZero() {
+				event.End = event.Start.Add(duration)
+			}
+			if !event.Start.IsZero() {
+				events = append(events, *event)
+			}
+			event = nil
+		case name == "SUMMARY":
+			event.Summary = icsUnescape(value)
+		case name == "DTSTART":
+			t, allDay, err := parseICSTime(value, params[1:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+			event.Start, event.AllDay, event.zone = t.Local(), allDay, t.Location()
+		case name == "DTEND":
+			t, _, err := parseICSTime(value, params[1:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+			event.End = t.Local()
+		case name == "DURATION":
+			d, err := parseICSDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+			duration = d
+		case name == "RRULE":
+			event.Rule = value
+		case name == "RDATE" || name == "EXDATE":
+			times, err := parseICSTimes(value, params[1:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+			if name == "RDATE" {
+				event.RDates = append(event.RDates, times...)
+			} else {
+				event.ExDates = append(event.ExDates, times...)
+			}
+		case name == "UID":
+			event.UID = value
+		case name == "RECURRENCE-ID":
+			t, _, err := parseICSTime(value, params[1:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+			event.RecurrenceID = t
+		case name == "STATUS":
+			event.Cancelled = strings.EqualFold(value, "CANCELLED")
+		}
+	}
+	return events, nil
+}
+
+// icsRule is an RRULE that occurrences can be expanded from.
+type icsRule struct {
+	freq      string
+	interval  int
+	count     int
+	until     time.Time
+	byDay     []time.Weekday
+	weekStart time.Weekday
+}
+
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// parseICSRule reads an RRULE. Daily, weekly, monthly and yearly rules
+// are supported, with BYDAY naming plain weekdays for daily and weekly
+// ones. Other parts, such as BYMONTHDAY or BYDAY=2TU, are errors.
+func parseICSRule(value string) (icsRule, error) {
+	rule := icsRule{interval: 1, weekStart: time.Monday}
+	for _, part := range strings.Split(value, ";") {
+		name, v, _ := strings.Cut(part, "=")
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			rule.freq = strings.ToUpper(v)
+		case "INTERVAL", "COUNT":
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return rule, fmt.Errorf("invalid %s %q", name, v)
+			}
+			if strings.EqualFold(name, "COUNT") {
+				rule.count = n
+			} else {
+				rule.interval = n
+			}
+		case "UNTIL":
+			t, allDay, err := parseICSTime(v, nil)
+			if err != nil {
+				return rule, err
+			}
+			if allDay {
+				t = t.AddDate(0, 0, 1).Add(-time.Second)
+			}
+			rule.until = t
+		case "BYDAY":
+			for _, day := range strings.Split(v, ",") {
+				weekday, ok := icsWeekdays[strings.ToUpper(day)]
+				if !ok {
+					return rule, fmt.Errorf("unsupported BYDAY=%s", v)
+				}
+				rule.byDay = append(rule.byDay, weekday)
+			}
+		case "WKST":
+			weekday, ok := icsWeekdays[strings.ToUpper(v)]
+			if !ok {
+				return rule, fmt.Errorf("invalid WKST=%s", v)
+			}
+			rule.weekStart = weekday
+		default:
+			return rule, fmt.Errorf("unsupported %s in RRULE", part)
+		}
+	}
+
+	switch rule.freq {
+	case "DAILY", "WEEKLY":
+	case "MONTHLY", "YEARLY":
+		if len(rule.byDay) > 0 {
+			return rule, fmt.Errorf("unsupported BYDAY with FREQ=%s", rule.freq)
+		}
+	default:
+		return rule, fmt.Errorf("unsupported FREQ=%s", rule.freq)
+	}
+	return rule, nil
+}
+
+// occurrences returns the starts the rule gives an event starting at
+// start, up to before last. They keep the wall clock time of start.
+func (rule icsRule) occurrences(start, last time.Time) []time.Time {
+	// Days of the week in the order they follow the week's start.
+	days := slices.Clone(rule.byDay)
+	sort.Slice(days, func(i, j int) bool {
+		return (days[i]-rule.weekStart+7)%7 < (days[j]-rule.weekStart+7)%7
+	})
+
+	var starts []time.Time
+	for period := 0; period < 100000; period++ {
+		var candidates []time.Time
+		switch rule.freq {
+		case "DAILY":
+			t := start.AddDate(0, 0, period*rule.interval)
+			if len(days) == 0 || slices.Contains(days, t.Weekday()) {
+				candidates = append(candidates, t)
+			}
+		case "WEEKLY":
+			if len(days) == 0 {
+				candidates = append(candidates, start.AddDate(0, 0, 7*period*rule.interval))
+				break
+			}
+			week := start.AddDate(0, 0, 7*period*rule.interval-int((start.Weekday()-rule.weekStart+7)%7))
+			for _, day := range days {
+				if t := week.AddDate(0, 0, int((day-rule.weekStart+7)%7)); !t.Before(start) {
+					candidates = append(candidates, t)
+				}
+			}
+		case "MONTHLY":
+			// Months without the day, like the 31st, are skipped.
+			if t := start.AddDate(0, period*rule.interval, 0); t.Day() == start.Day() {
+				candidates = append(candidates, t)
+			}
+		case "YEARLY":
+			if t := start.AddDate(period*rule.interval, 0, 0); t.Day() == start.Day() {
+				candidates = append(candidates, t)
+			}
+		}
+
+		for _, t := range candidates {
+			if !t.Before(last) || (!rule.until.IsZero() && t.After(rule.until)) ||
+				(rule.count > 0 && len(starts) == rule.count) {
+				return starts
+			}
+			starts = append(starts, t)
+		}
+	}
+	return starts
+}
+
+// expandICSEvent returns the occurrences of a recurring event that start
+// before last, leaving out those in ExDates and replaced.
+func expandICSEvent(event icsEvent, last time.Time, replaced []time.Time) ([]icsEvent, error) {
+	starts := []time.Time{event.Start}
+	if event.Rule != "" {
+		rule, err := parseICSRule(event.Rule)
+		if err != nil {
+			return nil, err
+		}
+		zone := event.zone
+		if zone == nil {
+			zone = time.Local
+		}
+		starts = rule.occurrences(event.Start.In(zone), last)
+	}
+	starts = append(starts, event.RDates...)
+
+	duration := event.End.Sub(event.Start)
+	var occurrences []icsEvent
+	for _, start := range starts {
+		if slices.ContainsFunc(event.ExDates, start.Equal) || slices.ContainsFunc(replaced, start.Equal) {
+			continue
+		}
+		occurrence := event
+		occurrence.Start = start.Local()
+		occurrence.End = occurrence.Start.Add(duration)
+		occurrence.Rule, occurrence.RDates, occurrence.ExDates = "", nil, nil
+		occurrences = append(occurrences, occurrence)
+	}
+	return occurrences, nil
+}
+
+// importableEvents keeps the timed, not cancelled events that start
+// between the dates of from and to and whose summary matches, in order
+// of their start. Recurring events are expanded into their occurrences;
+// those whose rule is not supported are skipped and counted.
+func importableEvents(events []icsEvent, from, to time.Time, match *regexp.Regexp) ([]icsEvent, int) {
+	first := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.Local)
+	last := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.Local).AddDate(0, 0, 1)
+
+	// Occurrences moved or cancelled by an event of their own.
+	replaced := map[string][]time.Time{}
+	for _, event := range events {
+		if !event.RecurrenceID.IsZero() {
+			replaced[event.UID] = append(replaced[event.UID], event.RecurrenceID)
+		}
+	}
+
+	var kept []icsEvent
+	unsupported := 0
+	for _, event := range events {
+		if event.AllDay || event.Cancelled || !event.End.After(event.Start) {
+			continue
+		}
+		if match != nil && !match.MatchString(event.Summary) {
+			continue
+		}
+
+		occurrences := []icsEvent{event}
+		if event.recurring() {
+			var err error
+			if occurrences, err = expandICSEvent(event, last, replaced[event.UID]); err != nil {
+				unsupported++
+				continue
+			}
+		}
+		for _, occurrence := range occurrences {
+			if !occurrence.Start.Before(first) && occurrence.Start.Before(last) {
+				kept = append(kept, occurrence)
+			}
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		return kept[i].Start.Before(kept[j].Start)
+	})
+	return kept, unsupported
+}
+
+// icsEventEntries turns events into START and STOP rows, naming the
+// activity after the summary.
+func icsEventEntries(events []icsEvent) []TimerEntry {
+	var entries []TimerEntry
+	for _, event := range events {
+		entries = append(entries, sessionEntries(Session{
+			Name:  strings.TrimSpace(event.Summary),
+			Start: event.Start,
+			End:   event.End,
+		})...)
+	}
+	return entries
+}
+
+// importICSEvents writes events to the workbook and records the project
+// their rules give each new activity.
+func importICSEvents(events []icsEvent) (added, skipped int, err error) {
+	added, skipped, err = appendEntries(icsEventEntries(events))
+	if err != nil {
+		return added, skipped, err
+	}
+	var names []string
+	for _, event := range events {
+		names = append(names, strings.TrimSpace(event.Summary))
+	}
+	return added, skipped, assignProjects(names)
+}
+
+func importICSCommand(args []string) error {
+	flags := flag.NewFlagSet("import-ics", flag.ContinueOnError)
+	fromText := flags.String("from", "", "first day to import (YYYY-MM-DD), the start of the file if empty")
+	toText := flags.String("to", "", "last day to import (YYYY-MM-DD), today if empty")
+	matchText := flags.String("match", "", "only events whose summary matches this regular expression")
+	dryRun := flags.Bool("dry-run", false, "show what would be imported without writing")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: import-ics [flags] FILE")
+	}
+
+	var match *regexp.Regexp
+	if *matchText != "" {
+		re, err := regexp.Compile("(?i)" + *matchText)
+		if err != nil {
+			return err
+		}
+		match = re
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	events, err := parseICS(file)
+	if err != nil {
+		return err
+	}
+
+	var from time.Time
+	to := time.Now()
+	if *fromText != "" {
+		if from, err = time.ParseInLocation("2006-01-02", *fromText, time.Local); err != nil {
+			return fmt.Errorf("invalid date %q", *fromText)
+		}
+	}
+	if *toText != "" {
+		if to, err = time.ParseInLocation("2006-01-02", *toText, time.Local); err != nil {
+			return fmt.Errorf("invalid date %q", *toText)
+		}
+	}
+
+	events, unsupported := importableEvents(events, from, to, match)
+	if unsupported > 0 {
+		fmt.Printf("Skipped %d recurring events with rules that cannot be expanded\n", unsupported)
+	}
+	if *dryRun {
+		projectFor := projectLookup()
+		for _, event := range events {
+			fmt.Printf("%s\t%s\t%s\t%s\n", event.Start.Format("2006-01-02 15:04"), event.End.Format("15:04"),
+				event.Summary, projectFor(strings.TrimSpace(event.Summary)))
+		}
+		fmt.Printf("%d events would be imported\n", len(events))
+		return nil
+	}
+
+	added, skipped, err := importICSEvents(events)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d entries, skipped %d duplicates\n", added, skipped)
+	return nil
+}
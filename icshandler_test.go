@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+const icsCalendar = "BEGIN:VCALENDAR\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Review\\, planning and a summary long enough to be \r\n" +
+	" folded\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20240304T090000\r\n" +
+	"DURATION:PT1H30M\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"SUMMARY:Alarm\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Standup\r\n" +
+	"DTSTART:20240304T080000Z\r\n" +
+	"DTEND:20240304T081500Z\r\n" +
+	"RRULE:FREQ=DAILY\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Holiday\r\n" +
+	"DTSTART;VALUE=DATE:20240305\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICS(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no time zone data:", err)
+	}
+
+	events, err := parseICS(strings.NewReader(icsCalendar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+
+	review := events[0]
+	if review.Summary != "Review, planning and a summary long enough to be folded" {
+		t.Errorf("summary = %q", review.Summary)
+	}
+	if want := time.Date(2024, 3, 4, 9, 0, 0, 0, berlin); !review.Start.Equal(want) {
+		t.Errorf("start = %v, want %v", review.Start, want)
+	}
+	if got := review.End.Sub(review.Start); got != 90*time.Minute {
+		t.Errorf("duration = %v, want 1h30m", got)
+	}
+
+	if !events[1].recurring() {
+		t.Error("RRULE event is not recurring")
+	}
+	if !events[2].AllDay || !events[2].Cancelled {
+		t.Errorf("holiday = %+v, want all day and cancelled", events[2])
+	}
+
+	// The review and the daily standup from March 4 to 10.
+	kept, unsupported := importableEvents(events, time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local),
+		time.Date(2024, 3, 10, 0, 0, 0, 0, time.Local), nil)
+	if len(kept) != 8 || unsupported != 0 {
+		t.Errorf("importable: %d kept and %d unsupported, want 8 and 0", len(kept), unsupported)
+	}
+}
+
+const icsRecurring = "BEGIN:VCALENDAR\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:weekly\r\n" +
+	"SUMMARY:Planning\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20240304T100000\r\n" +
+	"DTEND;TZID=Europe/Berlin:20240304T110000\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO,TH;COUNT=10\r\n" +
+	"EXDATE;TZID=Europe/Berlin:20240307T100000\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:weekly\r\n" +
+	"RECURRENCE-ID;TZID=Europe/Berlin:20240311T100000\r\n" +
+	"SUMMARY:Planning\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20240311T140000\r\n" +
+	"DTEND;TZID=Europe/Berlin:20240311T150000\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Board\r\n" +
+	"DTSTART:20240305T090000Z\r\n" +
+	"DTEND:20240305T100000Z\r\n" +
+	"RRULE:FREQ=MONTHLY;BYDAY=1TU\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestImportableEventsRecurring(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no time zone data:", err)
+	}
+
+	events, err := parseICS(strings.NewReader(icsRecurring))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, unsupported := importableEvents(events, time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local),
+		time.Date(2024, 4, 30, 0, 0, 0, 0, time.Local), nil)
+	if unsupported != 1 {
+		t.Errorf("%d unsupported, want the BYDAY=1TU rule", unsupported)
+	}
+
+	// Ten occurrences, less the excluded Thursday, with the second Monday
+	// moved to the afternoon. Those in April are after the change to
+	// summer time and keep their wall clock time.
+	want := []time.Time{
+		time.Date(2024, 3, 4, 10, 0, 0, 0, berlin),
+		time.Date(2024, 3, 11, 14, 0, 0, 0, berlin),
+		time.Date(2024, 3, 14, 10, 0, 0, 0, berlin),
+		time.Date(2024, 3, 18, 10, 0, 0, 0, berlin),
+		time.Date(2024, 3, 21, 10, 0, 0, 0, berlin),
+		time.Date(2024, 3, 25, 10, 0, 0, 0, berlin),
+		time.Date(2024, 3, 28, 10, 0, 0, 0, berlin),
+		time.Date(2024, 4, 1, 10, 0, 0, 0, berlin),
+		time.Date(2024, 4, 4, 10, 0, 0, 0, berlin),
+	}
+	if len(kept) != len(want) {
+		t.Fatalf("got %d occurrences, want %d: %+v", len(kept), len(want), kept)
+	}
+	for i, event := range kept {
+		if !event.Start.Equal(want[i]) || event.End.Sub(event.Start) != time.Hour {
+			t.Errorf("occurrence %d runs %v to %v, want from %v", i, event.Start, event.End, want[i])
+		}
+	}
+}
+
+func TestWriteICSLineFolds(t *testing.T) {
+	line := "DESCRIPTION:" + strings.Repeat("é", 100)
+	var buf bytes.Buffer
+	out := bufio.NewWriter(&buf)
+	writeICSLine(out, line)
+	out.Flush()
+
+	folded := strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n")
+	if len(folded) < 2 {
+		t.Fatalf("line was not folded: %q", buf.String())
+	}
+	var unfolded strings.Builder
+	for i, part := range folded {
+		if len(part) > 75 {
+			t.Errorf("line %d is %d octets", i, len(part))
+		}
+		if i > 0 {
+			part = strings.TrimPrefix(part, " ")
+		}
+		unfolded.WriteString(part)
+	}
+	if unfolded.String() != line {
+		t.Errorf("unfolded line differs, a character was split")
+	}
+}
+
+func TestWriteSessionsICSNotes(t *testing.T) {
+	var buf bytes.Buffer
+	err := writeSessionsICS(&buf, []Session{{
+		Name:     "Code",
+		Start:    time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC),
+		End:      time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC),
+		Duration: time.Hour,
+		Notes:    "Fixed the parser",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `DESCRIPTION:Fixed the parser\n\nTracked 01:00:00`; !strings.Contains(buf.String(), want) {
+		t.Errorf("missing %q in\n%s", want, buf.String())
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+var (
+	IdleThreshold = 5 * time.Minute
+	idleCheckRate = 5 * time.Second
+
+	lastActivity  = time.Now()
+	lastIdleCheck time.Time
+	idleStart     time.Time
+	idlePrompted  bool
+)
+
+// markActive records interaction with the window.
+func markActive() {
+	lastActivity = time.Now()
+}
+
+// currentIdle combines the desktop idle source with interaction in the
+// window, whichever saw the user more recently. Without a desktop source
+// only interaction in the window counts.
+func currentIdle() time.Duration {
+	idle := time.Since(lastActivity)
+	if system, ok := systemIdle(); ok && system < idle {
+		idle = system
+	}
+	return idle
+}
+
+// checkIdle is called from the update loop. Once the user comes back
+// after at least IdleThreshold without input while the timer was
+// counting, it asks what to do with the idle interval.
+func checkIdle() {
+	if time.Since(lastIdleCheck) < idleCheckRate {
+		return
+	}
+	lastIdleCheck = time.Now()
+
+	if !state.running || state.paused || idlePrompted {
+		idleStart = time.Time{}
+		return
+	}
+
+	idle := currentIdle()
+	if idle >= IdleThreshold {
+		start := time.Now().Add(-idle)
+		if idleStart.IsZero() || start.Before(idleStart) {
+			idleStart = start
+		}
+		return
+	}
+
+	if !idleStart.IsZero() {
+		end := time.Now().Add(-idle)
+		interval := end.Sub(idleStart)
+		idleStart = time.Time{}
+		if interval >= IdleThreshold {
+			promptIdle(interval, end)
+		}
+	}
+}
+
+// promptIdle asks whether to keep, discard or reassign the idle interval
+// that ended at end.
+func promptIdle(interval time.Duration, end time.Time) {
+	idlePrompted = true
+
+	w := windowMaker(App, "Idle")
+	w.SetCloseIntercept(func() {
+		idlePrompted = false
+		w.Close()
+	})
+
+	message := widget.NewLabel(fmt.Sprintf("You were idle for %s.", formatDuration(interval)))
+	message.Wrapping = fyne.TextWrapWord
+
+	reassignEntry := widget.NewEntry()
+	reassignEntry.SetPlaceHolder("Reassign to activity")
+
+	keepButton := button("Keep", func() {
+		idlePrompted = false
+		w.Close()
+	})
+	discardButton := button("Discard", func() {
+		trimIdle(interval, end, "IDLE", nameEntry.Text)
+		idlePrompted = false
+		w.Close()
+	})
+	reassignButton := button("Reassign", func() {
+		if reassignEntry.Text == "" {
+			slog.Info("Enter an activity to reassign the idle time to.")
+			return
+		}
+		trimIdle(interval, end, "REASSIGN", reassignEntry.Text)
+		idlePrompted = false
+		w.Close()
+	})
+
+	w.SetContent(container.NewVBox(
+		message,
+		reassignEntry,
+		container.NewCenter(container.NewHBox(keepButton, discardButton, reassignButton)),
+	))
+	w.Show()
+}
+
+// trimIdle takes the idle interval off the running session and records
+// where it went. The row is stamped with the end of the interval, so a
+// REASSIGN row becomes a session over the idle time itself. Like other
+// timer events it runs the hooks and webhooks.
+func trimIdle(interval time.Duration, end time.Time, eventType string, name string) {
+	if interval > Elapsed {
+		interval = Elapsed
+	}
+	Elapsed -= interval
+	timeLabel.SetText(formatDuration(Elapsed))
+
+	entry := TimerEntry{
+		Timestamp: end,
+		Event:     eventType,
+		Name:      name,
+		Duration:  interval,
+	}
+	recordEntry(entry)
+}
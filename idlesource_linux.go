@@ -0,0 +1,70 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	inputInterrupts uint64
+	inputChanged    = time.Now()
+)
+
+// systemIdle reports how long the desktop has had no keyboard or mouse
+// input. It asks xprintidle first (X11 and XWayland) and falls back to
+// watching the input device counters in /proc/interrupts.
+func systemIdle() (time.Duration, bool) {
+	if out, err := exec.Command("xprintidle").Output(); err == nil {
+		ms, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+		if err == nil {
+			return time.Duration(ms) * time.Millisecond, true
+		}
+	}
+
+	count, ok := readInputInterrupts()
+	if !ok {
+		return 0, false
+	}
+	if count != inputInterrupts {
+		inputInterrupts = count
+		inputChanged = time.Now()
+	}
+	return time.Since(inputChanged), true
+}
+
+// readInputInterrupts sums the interrupt counts of keyboard, mouse and
+// USB HID devices.
+func readInputInterrupts() (uint64, bool) {
+	file, err := os.Open("/proc/interrupts")
+	if err != nil {
+		return 0, false
+	}
+	defer file.Close()
+
+	var total uint64
+	found := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.Contains(line, "i8042") && !strings.Contains(line, "hid") &&
+			!strings.Contains(line, "xhci") && !strings.Contains(line, "ehci") {
+			continue
+		}
+
+		found = true
+		for _, field := range strings.Fields(line)[1:] {
+			n, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				break
+			}
+			total += n
+		}
+	}
+	return total, found
+}
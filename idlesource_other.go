@@ -0,0 +1,11 @@
+//go:build !linux
+
+package main
+
+import "time"
+
+// systemIdle has no desktop idle source outside Linux yet, so idle
+// detection goes by interaction with the window there.
+func systemIdle() (time.Duration, bool) {
+	return 0, false
+}
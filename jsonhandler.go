@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"strings"
+	"time"
+)
+
+// entryPayload is how a TimerEntry is exported: ISO-8601 timestamps and
+// durations in whole seconds. Hooks get hookPayload instead.
+type entryPayload struct {
+	Event     string   `json:"event"`
+	Name      string   `json:"name"`
+	Tags      []string `json:"tags,omitempty"`
+	Timestamp string   `json:"timestamp"`
+	Duration  int64    `json:"duration"`
+}
+
+func newEntryPayload(entry TimerEntry) entryPayload {
+	return entryPayload{
+		Event:     entry.Event,
+		Name:      entry.Name,
+		Tags:      activityTags(entry.Name),
+		Timestamp: entry.Timestamp.Format(time.RFC3339),
+		Duration:  int64(entry.Duration.Seconds()),
+	}
+}
+
+// sessionPayload is how a Session is handed to other programs.
+type sessionPayload struct {
+	Name     string   `json:"name"`
+	Tags     []string `json:"tags,omitempty"`
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Duration int64    `json:"duration"`
+}
+
+func newSessionPayload(session Session) sessionPayload {
+	return sessionPayload{
+		Name:     session.Name,
+		Tags:     activityTags(session.Name),
+		Start:    session.Start.Format(time.RFC3339),
+		End:      session.End.Format(time.RFC3339),
+		Duration: int64(session.Duration.Seconds()),
+	}
+}
+
+// activityTags returns the #tags written in an activity name, without
+// the #.
+func activityTags(name string) []string {
+	var tags []string
+	for _, word := range strings.Fields(name) {
+		if len(word) > 1 && strings.HasPrefix(word, "#") {
+			tags = append(tags, word[1:])
+		}
+	}
+	return tags
+}
+
+// exportFilter keeps entries of one activity and/or with one tag. Empty
+// fields match everything.
+type exportFilter struct {
+	Activity string
+	Tag      string
+}
+
+func (f exportFilter) match(name string) bool {
+	if f.Activity != "" && !strings.EqualFold(name, f.Activity) {
+		return false
+	}
+	if f.Tag != "" {
+		for _, tag := range activityTags(name) {
+			if strings.EqualFold(tag, strings.TrimPrefix(f.Tag, "#")) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// exportJSON writes the events or sessions of query, as one JSON array
+// or as one object per line.
+func exportJSON(w io.Writer, query sessionQuery, sessions bool, ndjson bool) error {
+	var items []any
+	if sessions {
+		matched, err := filteredSessions(query)
+		if err != nil {
+			return err
+		}
+		for _, session := range matched {
+			items = append(items, newSessionPayload(session))
+		}
+	} else {
+		entries, err := readEntries(query.From, query.To)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if query.Filter.match(entry.Name) {
+				items = append(items, newEntryPayload(entry))
+			}
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	if ndjson {
+		for _, item := range items {
+			if err := encoder.Encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if items == nil {
+		items = []any{}
+	}
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(items)
+}
+
+func exportJSONCommand(args []string) error {
+	flags := flag.NewFlagSet("export-json", flag.ContinueOnError)
+	sessions := flags.Bool("sessions", false, "export sessions instead of events")
+	ndjson := flags.Bool("ndjson", false, "write one JSON object per line")
+	shared := sessionFilterFlags(flags, "")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	query, out, err := shared.open()
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return exportJSON(out, query, *sessions, *ndjson)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const timeclockLayout = "2006/01/02 15:04:05"
+
+// timeclockAccount names the account of an activity as project:activity,
+// or just activity when project is empty. Colons in names would add
+// account levels and runs of spaces would end the account, so both are
+// replaced.
+func timeclockAccount(name, project string) string {
+	var words []string
+	for _, word := range strings.Fields(name) {
+		if !(len(word) > 1 && strings.HasPrefix(word, "#")) {
+			words = append(words, strings.ReplaceAll(word, ":", "-"))
+		}
+	}
+	account := strings.Join(words, " ")
+
+	if project != "" {
+		project = strings.Join(strings.Fields(strings.ReplaceAll(project, ":", "-")), " ")
+		account = project + ":" + account
+	}
+	return account
+}
+
+// writeTimeclock writes sessions as ledger timeclock check-in and
+// check-out lines. The check-out is the start plus the tracked time, so
+// pauses are left out as they are everywhere else.
+func writeTimeclock(w io.Writer, sessions []Session) error {
+	projectFor := projectLookup()
+	out := bufio.NewWriter(w)
+	for _, session := range sessions {
+		account := timeclockAccount(session.Name, projectFor(session.Name))
+		fmt.Fprintf(out, "i %s %s\n", session.Start.Format(timeclockLayout), account)
+		fmt.Fprintf(out, "o %s\n", session.Start.Add(session.Duration).Format(timeclockLayout))
+	}
+	return out.Flush()
+}
+
+// exportTimeclock writes the sessions of query in timeclock format.
+func exportTimeclock(w io.Writer, query sessionQuery) error {
+	sessions, err := filteredSessions(query)
+	if err != nil {
+		return err
+	}
+	return writeTimeclock(w, sessions)
+}
+
+func exportLedgerCommand(args []string) error {
+	flags := flag.NewFlagSet("export-ledger", flag.ContinueOnError)
+	shared := sessionFilterFlags(flags, "")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	query, out, err := shared.open()
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return exportTimeclock(out, query)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+var (
+	LogMaxSize int64 = 1 << 20
+	LogBackups       = 3
+	LogLevel         = slog.LevelInfo
+
+	logFile   *rotatingFile
+	logRecent = &recentLines{max: 500}
+)
+
+// rotatingFile is a log file that is renamed to name.1 once it grows past
+// LogMaxSize, keeping LogBackups old files.
+type rotatingFile struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	size int64
+}
+
+func openRotatingFile(path string) (*rotatingFile, error) {
+	r := &rotatingFile{path: path}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.size+int64(len(p)) > LogMaxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) rotate() error {
+	r.file.Close()
+	for i := LogBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	if LogBackups > 0 {
+		os.Rename(r.path, r.path+".1")
+	} else {
+		os.Remove(r.path)
+	}
+	return r.open()
+}
+
+// recentLines keeps the last lines written for the log viewer.
+type recentLines struct {
+	mu    sync.Mutex
+	max   int
+	lines []string
+}
+
+func (l *recentLines) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.lines = append(l.lines, strings.TrimRight(string(p), "\n"))
+	if len(l.lines) > l.max {
+		l.lines = l.lines[len(l.lines)-l.max:]
+	}
+	return len(p), nil
+}
+
+func (l *recentLines) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return strings.Join(l.lines, "\n")
+}
+
+// statusHandler writes records as text and shows records of level Info
+// and above in LogEntry. Errors stay there; anything else is cleared
+// after LogResetDelay.
+type statusHandler struct {
+	slog.Handler
+	attrs []slog.Attr
+}
+
+func (h *statusHandler) Handle(ctx context.Context, record slog.Record) error {
+	err := h.Handler.Handle(ctx, record)
+
+	if LogEntry != nil && record.Level >= slog.LevelInfo {
+		var status bytes.Buffer
+		status.WriteString(record.Message)
+		appendValue := func(a slog.Attr) bool {
+			fmt.Fprint(&status, ": ", a.Value)
+			return true
+		}
+		for _, a := range h.attrs {
+			appendValue(a)
+		}
+		record.Attrs(appendValue)
+
+		LogEntry.SetText(status.String())
+		if record.Level < slog.LevelError {
+			resetLogText()
+		}
+	}
+	return err
+}
+
+func (h *statusHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &statusHandler{Handler: h.Handler.WithAttrs(attrs), attrs: append(h.attrs[:len(h.attrs):len(h.attrs)], attrs...)}
+}
+
+func (h *statusHandler) WithGroup(name string) slog.Handler {
+	return &statusHandler{Handler: h.Handler.WithGroup(name), attrs: h.attrs}
+}
+
+// logFilePath returns timer.log in the data directory.
+func logFilePath() string {
+	return filepath.Join(dataDir(), "timer.log")
+}
+
+// initLogging makes the default slog logger write to the rotating log
+// file and the log viewer, and show status in LogEntry. If the file
+// cannot be opened, logging still goes to the viewer.
+func initLogging() error {
+	var out io.Writer = logRecent
+	file, err := openRotatingFile(logFilePath())
+	if err == nil {
+		logFile = file
+		out = io.MultiWriter(logRecent, file)
+	}
+
+	slog.SetDefault(slog.New(&statusHandler{
+		Handler: slog.NewTextHandler(out, &slog.HandlerOptions{Level: LogLevel}),
+	}))
+	return err
+}
+
+// showLogViewer opens a window with the recent log lines.
+func showLogViewer() {
+	w := windowMaker(App, "Logs")
+
+	text := widget.NewLabel(logRecent.String())
+	text.Wrapping = fyne.TextWrapWord
+	text.TextStyle.Monospace = true
+	scroll := container.NewVScroll(text)
+	scroll.ScrollToBottom()
+
+	refreshButton := button("Refresh", func() {
+		text.SetText(logRecent.String())
+		scroll.ScrollToBottom()
+	})
+
+	w.SetContent(container.NewBorder(
+		widget.NewLabel(logFilePath()), refreshButton, nil, nil,
+		scroll,
+	))
+	w.Show()
+}
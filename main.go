@@ -1,23 +1,49 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"os/exec"
+	"log/slog"
+	"os"
 	"sync"
 	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
 
 	"fyne.io/fyne/v2/widget"
 )
 
 func main() {
 	Wg = sync.WaitGroup{}
+	flag.StringVar(&flagDataDir, "data-dir", "", "directory to keep the workbook in, overriding the config file")
+	flag.StringVar(&flagFile, "file", "", "workbook to use, overriding the config file")
+	flag.Parse()
+	configErr := loadConfig()
+	dataErr := initDataDir()
+
+	// Run a command instead of the window if one was given
+	if flag.NArg() > 0 {
+		if configErr == nil {
+			configErr = dataErr
+		}
+		if configErr == nil {
+			initExcelFile()
+			configErr = runCommand(flag.Args())
+		}
+		if configErr != nil {
+			fmt.Fprintln(os.Stderr, configErr)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Create app and window
 	App = app.New()
 	App.SetIcon(ResourceIconPng)
+	App.Lifecycle().SetOnEnteredForeground(markActive)
 	window := windowMaker(App, "Time Tracker")
 	window.SetMaster()
 
@@ -27,17 +53,45 @@ func main() {
 
 	nameEntry = widget.NewEntry()
 	nameEntry.SetPlaceHolder("Enter activity name")
+	notesEntry = widget.NewMultiLineEntry()
+	notesEntry.SetPlaceHolder("Notes, saved when the timer stops")
+	notesEntry.SetMinRowsVisible(2)
 
 	LogEntry = widget.NewLabel("Logs:...")
 	LogEntry.Wrapping = fyne.TextWrapWord
 	LogEntry.Alignment = fyne.TextAlignCenter
 	LogEntry.TextStyle.Italic = true
 	LogEntry.TextStyle.Bold = true
+	if err := initLogging(); err != nil {
+		slog.Error("Error opening log file", "err", err)
+	}
+	if configErr != nil {
+		slog.Error("Error loading config", "err", configErr)
+	}
+	if dataErr != nil {
+		slog.Error("Error preparing data directory", "err", dataErr)
+	}
+
+	budgetLabel = widget.NewLabel("")
+	budgetLabel.Alignment = fyne.TextAlignCenter
+	budgetBar = widget.NewProgressBar()
+	budgetBar.TextFormatter = func() string { return "" }
+	budgetBar.Hide()
+	budgetButton := button("Budget", showBudgetSettings)
+	reminderButton := button("Reminders", showReminderSettings)
+	settingsButton := button("Settings", showSettings)
+	logsButton := button("Logs", showLogViewer)
+
+	pomodoroLabel = widget.NewLabel("")
+	pomodoroCheck := widget.NewCheck("Pomodoro", setPomodoro)
+	pomodoroSettings := button("...", showPomodoroSettings)
 
 	// Create buttons
 	startButton := button("Start", startTimer)
 	pauseButton := button("Pause", pauseTimer)
 	stopButton := button("Stop", stopTimer)
+	undoButton := button("Undo", undoTimer)
+	redoButton := button("Redo", redoTimer)
 	exitButton := button("Exit", exitApp)
 	exitButton.Importance = widget.HighImportance
 
@@ -46,6 +100,8 @@ func main() {
 			startButton,
 			pauseButton,
 			stopButton,
+			undoButton,
+			redoButton,
 		),
 	)
 
@@ -53,45 +109,56 @@ func main() {
 	content := container.NewVBox(
 		//draggableHeader,
 		nameEntry,
+		notesEntry,
 		timeLabel,
+		budgetBar,
+		budgetLabel,
 		buttonContainer,
+		container.NewCenter(
+			container.NewHBox(
+				pomodoroCheck,
+				pomodoroSettings,
+				budgetButton,
+				reminderButton,
+				settingsButton,
+				logsButton,
+			),
+		),
+		container.NewCenter(pomodoroLabel),
 		exitButton,
 		LogEntry,
 	)
 
 	// Initialize Excel file
 	initExcelFile()
+	refreshPomodoroCount()
+	pomodoroCheck.SetChecked(StartPomodoro)
 
 	// Start update loop
+	Wg.Add(3)
 	go updateTimeDisplay()
+	go runReminders()
+	go runWebhooks()
+	go pushOnStart()
+	startServer()
 
 	// Show and run app
 	window.SetContent(content)
-	window.SetCloseIntercept(func() {
-		logEvent("EXIT")
-		Wg.Add(1)
-		LogEntry.SetText("Saving to Excel...")
-		// Force immediate Excel save before closing
-		go func() {
-			time.Sleep(100 * time.Millisecond) // Let saveToExcel finish
-			// Path to your batch file
-			batFile := "_Git_Push.bat"
-
-			// Create command to execute the batch file
-			cmd := exec.Command("cmd.exe", "/C", batFile)
-
-			// Capture output (optional)
-			output, err := cmd.CombinedOutput()
-			if err != nil {
-				fmt.Printf("Error executing batch file: %v\n", err)
-				return
-			}
-
-			// Print output
-			fmt.Printf("Batch file output:\n%s\n", output)
-			App.Quit()
-		}()
-	})
+	window.SetMainMenu(mainMenu())
+	window.Canvas().AddShortcut(&desktop.CustomShortcut{
+		KeyName:  fyne.KeyZ,
+		Modifier: fyne.KeyModifierShortcutDefault,
+	}, func(fyne.Shortcut) { undoTimer() })
+	window.Canvas().AddShortcut(&desktop.CustomShortcut{
+		KeyName:  fyne.KeyY,
+		Modifier: fyne.KeyModifierShortcutDefault,
+	}, func(fyne.Shortcut) { redoTimer() })
+	window.SetCloseIntercept(shutdown)
+	handleSignals()
+	if setupTray(window, shutdown) {
+		hideButton := button("Hide to tray", hideToTray)
+		content.Add(hideButton)
+	}
 
 	window.ShowAndRun()
 
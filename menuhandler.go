@@ -0,0 +1,457 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// mainMenu builds the menu bar of the main window.
+func mainMenu() *fyne.MainMenu {
+	return fyne.NewMainMenu(
+		fyne.NewMenu("Data",
+			fyne.NewMenuItem("Export CSV...", showExportCSV),
+			fyne.NewMenuItem("Export JSON...", showExportJSON),
+			fyne.NewMenuItem("Export iCalendar...", showExportICS),
+			fyne.NewMenuItem("Export Timewarrior...", showExportTimew),
+			fyne.NewMenuItem("Export ledger timeclock...", showExportLedger),
+			fyne.NewMenuItem("Export org-mode...", showExportOrg),
+			fyne.NewMenuItem("Invoice...", showInvoice),
+			fyne.NewMenuItem("Timesheet PDF...", showTimesheet),
+			fyne.NewMenuItemSeparator(),
+			fyne.NewMenuItem("Import CSV...", showImportCSV),
+			fyne.NewMenuItem("Import iCalendar...", showImportICS),
+			fyne.NewMenuItem("Import Timewarrior...", func() {
+				showImportSessions("Import Timewarrior", nil, parseTimew)
+			}),
+			fyne.NewMenuItem("Import Toggl or Clockify...", showImportTracker),
+		),
+	)
+}
+
+// dateRangeEntries returns entries for the first and last day of a
+// range, defaulting to the last 30 days.
+func dateRangeEntries() (*widget.Entry, *widget.Entry) {
+	from := widget.NewEntry()
+	from.SetText(time.Now().AddDate(0, 0, -30).Format("2006-01-02"))
+	to := widget.NewEntry()
+	to.SetText(time.Now().Format("2006-01-02"))
+	return from, to
+}
+
+// showExport opens a window asking for a date range and options, then
+// for a file to write with export.
+func showExport(title string, fileExt string, options []*widget.FormItem,
+	export func(w io.Writer, from, to time.Time) error) {
+	w := windowMaker(App, title)
+
+	fromEntry, toEntry := dateRangeEntries()
+	exportButton := button("Export...", func() {
+		from, to, err := parseDateRange(fromEntry.Text, toEntry.Text)
+		if err != nil {
+			slog.Warn("Invalid date range", "err", err)
+			return
+		}
+
+		save := dialog.NewFileSave(func(file fyne.URIWriteCloser, err error) {
+			if err != nil || file == nil {
+				return
+			}
+			defer file.Close()
+
+			if err := export(file, from, to); err != nil {
+				slog.Error("Error exporting", "err", err)
+				return
+			}
+			slog.Info("Exported", "file", file.URI().Path())
+			w.Close()
+		}, w)
+		save.SetFileName(fmt.Sprintf("timer_%s_%s%s", from.Format("20060102"), to.Format("20060102"), fileExt))
+		save.Show()
+	})
+
+	form := widget.NewForm(
+		widget.NewFormItem("From", fromEntry),
+		widget.NewFormItem("To", toEntry),
+	)
+	for _, item := range options {
+		form.AppendItem(item)
+	}
+	w.SetContent(container.NewVBox(form, exportButton))
+	w.Show()
+}
+
+// filterItems returns form items for an export filter and a function
+// reading it into the query of a date range.
+func filterItems() ([]*widget.FormItem, func(from, to time.Time) sessionQuery) {
+	activityEntry := widget.NewEntry()
+	tagEntry := widget.NewEntry()
+	items := []*widget.FormItem{
+		widget.NewFormItem("Activity", activityEntry),
+		widget.NewFormItem("Tag", tagEntry),
+	}
+	return items, func(from, to time.Time) sessionQuery {
+		return sessionQuery{From: from, To: to, Filter: exportFilter{Activity: activityEntry.Text, Tag: tagEntry.Text}}
+	}
+}
+
+func showExportCSV() {
+	kindSelect := widget.NewSelect([]string{"Events", "Sessions"}, nil)
+	kindSelect.SetSelected("Events")
+	delimiterEntry := widget.NewEntry()
+	delimiterEntry.SetText(",")
+
+	showExport("Export CSV", ".csv", []*widget.FormItem{
+		widget.NewFormItem("Export", kindSelect),
+		widget.NewFormItem("Delimiter", delimiterEntry),
+	}, func(w io.Writer, from, to time.Time) error {
+		delimiter, err := parseDelimiter(delimiterEntry.Text)
+		if err != nil {
+			return err
+		}
+		return exportCSV(w, from, to, kindSelect.Selected == "Sessions", delimiter)
+	})
+}
+
+func showExportJSON() {
+	kindSelect := widget.NewSelect([]string{"Events", "Sessions"}, nil)
+	kindSelect.SetSelected("Events")
+	formatSelect := widget.NewSelect([]string{"JSON", "NDJSON"}, nil)
+	formatSelect.SetSelected("JSON")
+	items, query := filterItems()
+
+	showExport("Export JSON", ".json", append([]*widget.FormItem{
+		widget.NewFormItem("Export", kindSelect),
+		widget.NewFormItem("Format", formatSelect),
+	}, items...), func(w io.Writer, from, to time.Time) error {
+		return exportJSON(w, query(from, to), kindSelect.Selected == "Sessions", formatSelect.Selected == "NDJSON")
+	})
+}
+
+func showExportICS() {
+	items, query := filterItems()
+	showExport("Export iCalendar", ".ics", items, func(w io.Writer, from, to time.Time) error {
+		return exportICS(w, query(from, to))
+	})
+}
+
+func showExportTimew() {
+	items, query := filterItems()
+	showExport("Export Timewarrior", ".data", items, func(w io.Writer, from, to time.Time) error {
+		return exportTimew(w, query(from, to))
+	})
+}
+
+func showExportLedger() {
+	items, query := filterItems()
+	showExport("Export ledger timeclock", ".timeclock", items, func(w io.Writer, from, to time.Time) error {
+		return exportTimeclock(w, query(from, to))
+	})
+}
+
+func showExportOrg() {
+	groupSelect := widget.NewSelect([]string{"Day", "Project"}, nil)
+	groupSelect.SetSelected("Day")
+	items, query := filterItems()
+
+	showExport("Export org-mode", ".org", append([]*widget.FormItem{
+		widget.NewFormItem("Group by", groupSelect),
+	}, items...), func(w io.Writer, from, to time.Time) error {
+		return exportOrg(w, query(from, to), groupSelect.Selected == "Project")
+	})
+}
+
+func showInvoice() {
+	clientEntry := widget.NewEntry()
+	numberEntry := widget.NewEntry()
+
+	showExport("Invoice", ".xlsx", []*widget.FormItem{
+		widget.NewFormItem("Client", clientEntry),
+		widget.NewFormItem("Number", numberEntry),
+	}, func(w io.Writer, from, to time.Time) error {
+		lines, err := buildInvoice(from, to, clientEntry.Text)
+		if err != nil {
+			return err
+		}
+		if len(lines) == 0 {
+			return fmt.Errorf("no sessions to bill")
+		}
+		if unrated := unratedActivities(lines); len(unrated) > 0 {
+			slog.Warn("No rate for " + strings.Join(unrated, ", "))
+		}
+		return writeInvoice(w, lines, numberEntry.Text, clientEntry.Text, from, to)
+	})
+}
+
+func showTimesheet() {
+	authorEntry := widget.NewEntry()
+	authorEntry.SetText(currentUserName())
+	items, query := filterItems()
+
+	showExport("Timesheet", ".pdf", append([]*widget.FormItem{
+		widget.NewFormItem("Name", authorEntry),
+	}, items...), func(w io.Writer, from, to time.Time) error {
+		return exportTimesheet(w, query(from, to), authorEntry.Text)
+	})
+}
+
+// showImportCSV picks a CSV file, then lets each field be mapped to one
+// of its columns before importing.
+func showImportCSV() {
+	w := windowMaker(App, "Import CSV")
+
+	delimiterEntry := widget.NewEntry()
+	delimiterEntry.SetText(",")
+	mappingForm := widget.NewForm()
+	status := widget.NewLabel("")
+	status.Wrapping = fyne.TextWrapWord
+
+	var header []string
+	var records [][]string
+	selects := map[string]*widget.Select{}
+
+	importButton := button("Import", func() {
+		if header == nil {
+			slog.Warn("Choose a CSV file first")
+			return
+		}
+
+		mapping := map[string]string{}
+		for field, s := range selects {
+			mapping[field] = s.Selected
+		}
+		entries, err := csvEntries(header, records, mapping)
+		if err != nil {
+			slog.Warn("Cannot import CSV", "err", err)
+			return
+		}
+		added, skipped, err := appendEntries(entries)
+		if err != nil {
+			slog.Error("Error importing CSV", "err", err)
+			return
+		}
+		slog.Info(fmt.Sprintf("Imported %d entries, skipped %d duplicates", added, skipped))
+		w.Close()
+	})
+
+	chooseButton := button("Choose file...", func() {
+		delimiter, err := parseDelimiter(delimiterEntry.Text)
+		if err != nil {
+			slog.Warn("Invalid delimiter", "err", err)
+			return
+		}
+
+		dialog.ShowFileOpen(func(file fyne.URIReadCloser, err error) {
+			if err != nil || file == nil {
+				return
+			}
+			defer file.Close()
+
+			header, records, err = readCSV(file, delimiter)
+			if err != nil {
+				slog.Error("Error reading CSV", "err", err)
+				return
+			}
+
+			options := append([]string{""}, header...)
+			guessed := guessCSVMapping(header)
+			mappingForm.Items = nil
+			for _, field := range csvFields {
+				s := widget.NewSelect(options, nil)
+				s.SetSelected(guessed[field])
+				selects[field] = s
+				mappingForm.Append(field, s)
+			}
+			mappingForm.Refresh()
+			status.SetText(fmt.Sprintf("%s: %d rows. Map timestamp and event, or start and end.",
+				file.URI().Name(), len(records)))
+		}, w)
+	})
+
+	w.SetContent(container.NewVScroll(container.NewVBox(
+		widget.NewForm(widget.NewFormItem("Delimiter", delimiterEntry)),
+		chooseButton,
+		status,
+		mappingForm,
+		importButton,
+	)))
+	w.Show()
+}
+
+// showImportICS picks an iCalendar file and lists its events in the date
+// range, to choose which become time entries.
+func showImportICS() {
+	w := windowMaker(App, "Import iCalendar")
+
+	fromEntry, toEntry := dateRangeEntries()
+	eventList := container.NewVBox()
+	status := widget.NewLabel("")
+	status.Wrapping = fyne.TextWrapWord
+
+	var events []icsEvent
+	var checks []*widget.Check
+
+	importButton := button("Import", func() {
+		var selected []icsEvent
+		for i, check := range checks {
+			if check.Checked {
+				selected = append(selected, events[i])
+			}
+		}
+		if len(selected) == 0 {
+			slog.Warn("No events selected")
+			return
+		}
+
+		added, skipped, err := importICSEvents(selected)
+		if err != nil {
+			showSaveError("Error importing events", err)
+			return
+		}
+		slog.Info(fmt.Sprintf("Imported %d entries, skipped %d duplicates", added, skipped))
+		w.Close()
+	})
+
+	chooseButton := button("Choose file...", func() {
+		from, to, err := parseDateRange(fromEntry.Text, toEntry.Text)
+		if err != nil {
+			slog.Warn("Invalid date range", "err", err)
+			return
+		}
+
+		dialog.ShowFileOpen(func(file fyne.URIReadCloser, err error) {
+			if err != nil || file == nil {
+				return
+			}
+			defer file.Close()
+
+			all, err := parseICS(file)
+			if err != nil {
+				slog.Error("Error reading iCalendar file", "err", err)
+				return
+			}
+
+			var unsupported int
+			events, unsupported = importableEvents(all, from, to, nil)
+			checks = nil
+			eventList.RemoveAll()
+			projectFor := projectLookup()
+			for _, event := range events {
+				label := fmt.Sprintf("%s-%s %s", event.Start.Format("2006-01-02 15:04"),
+					event.End.Format("15:04"), event.Summary)
+				if project := projectFor(strings.TrimSpace(event.Summary)); project != "" {
+					label += " (" + project + ")"
+				}
+				check := widget.NewCheck(label, nil)
+				check.SetChecked(true)
+				checks = append(checks, check)
+				eventList.Add(check)
+			}
+			status.SetText(fmt.Sprintf("%s: %d events, %d recurring events with unsupported rules skipped.",
+				file.URI().Name(), len(events), unsupported))
+		}, w)
+	})
+
+	w.SetContent(container.NewBorder(
+		container.NewVBox(
+			widget.NewForm(
+				widget.NewFormItem("From", fromEntry),
+				widget.NewFormItem("To", toEntry),
+			),
+			chooseButton,
+			status,
+		),
+		importButton, nil, nil,
+		container.NewVScroll(eventList),
+	))
+	w.Show()
+}
+
+// showImportSessions picks a file of another tracker, reads it with
+// parse and shows its sessions before importing them. options are shown
+// above the file button for parse to read.
+func showImportSessions(title string, options []*widget.FormItem, parse func(r io.Reader) ([]importedSession, error)) {
+	w := windowMaker(App, title)
+
+	sessionList := container.NewVBox()
+	status := widget.NewLabel("")
+	status.Wrapping = fyne.TextWrapWord
+	var sessions []importedSession
+
+	importButton := button("Import", func() {
+		if len(sessions) == 0 {
+			slog.Warn("Choose a file first")
+			return
+		}
+		added, skipped, err := importSessions(sessions)
+		if err != nil {
+			showSaveError("Error importing sessions", err)
+			return
+		}
+		slog.Info(fmt.Sprintf("Imported %d entries, skipped %d duplicates", added, skipped))
+		w.Close()
+	})
+
+	chooseButton := button("Choose file...", func() {
+		dialog.ShowFileOpen(func(file fyne.URIReadCloser, err error) {
+			if err != nil || file == nil {
+				return
+			}
+			defer file.Close()
+
+			sessions, err = parse(file)
+			if err != nil {
+				slog.Error("Error reading file", "err", err)
+				return
+			}
+			sessionList.RemoveAll()
+			for _, session := range sessions {
+				label := fmt.Sprintf("%s-%s %s", session.Start.Format("2006-01-02 15:04"),
+					session.End.Format("15:04"), session.Name)
+				if session.Project != "" {
+					label += " (" + session.Project + ")"
+				}
+				sessionList.Add(widget.NewLabel(label))
+			}
+			status.SetText(fmt.Sprintf("%s: %d sessions.", file.URI().Name(), len(sessions)))
+		}, w)
+	})
+
+	w.SetContent(container.NewBorder(
+		container.NewVBox(widget.NewForm(options...), chooseButton, status),
+		importButton, nil, nil,
+		container.NewVScroll(sessionList),
+	))
+	w.Show()
+}
+
+// showImportTracker imports a Toggl Track or Clockify report, with the
+// delimiter and date format it was written with.
+func showImportTracker() {
+	delimiterSelect := widget.NewSelect([]string{",", ";", "Tab"}, nil)
+	delimiterSelect.SetSelected(",")
+	dateSelect := widget.NewSelect(append([]string{"Detect"}, trackerDateFormatNames()...), nil)
+	dateSelect.SetSelected("Detect")
+
+	showImportSessions("Import Toggl or Clockify", []*widget.FormItem{
+		widget.NewFormItem("Delimiter", delimiterSelect),
+		widget.NewFormItem("Date format", dateSelect),
+	}, func(r io.Reader) ([]importedSession, error) {
+		delimiter, err := parseDelimiter(delimiterSelect.Selected)
+		if err != nil {
+			return nil, err
+		}
+		dateFormat := dateSelect.Selected
+		if dateFormat == "Detect" {
+			dateFormat = ""
+		}
+		return parseTrackerCSV(r, delimiter, dateFormat)
+	})
+}
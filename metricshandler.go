@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Counters for the Prometheus endpoint. They count the rows this process
+// writes from its start, as Prometheus counters do.
+var (
+	metricsMu          sync.Mutex
+	trackedSeconds     = map[string]float64{}
+	eventCounts        = map[string]int64{}
+	storageWriteErrors atomic.Int64
+
+	// undoneSeconds and undoneEvents hold what undone rows had counted,
+	// so the counters never go down and a row written again after an
+	// undo is not counted twice.
+	undoneSeconds = map[string]float64{}
+	undoneEvents  = map[string]int64{}
+)
+
+// recordMetrics counts a row written to the workbook and the time it
+// adds to its activity.
+func recordMetrics(entry TimerEntry) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+
+	if undoneEvents[entry.Event] > 0 {
+		undoneEvents[entry.Event]--
+	} else {
+		eventCounts[entry.Event]++
+	}
+	if countsTowardsTotal(entry.Event) {
+		seconds := entry.Duration.Seconds()
+		counted := min(seconds, undoneSeconds[entry.Name])
+		undoneSeconds[entry.Name] -= counted
+		trackedSeconds[entry.Name] += seconds - counted
+	}
+}
+
+// forgetMetrics notes a row removed by undo.
+func forgetMetrics(entry TimerEntry) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+
+	undoneEvents[entry.Event]++
+	if countsTowardsTotal(entry.Event) {
+		undoneSeconds[entry.Name] += entry.Duration.Seconds()
+	}
+}
+
+// storedCounts sums every row in the workbook, for "timer serve", which
+// writes none itself. An undo lowers them, which Prometheus takes for a
+// counter reset.
+func storedCounts(today time.Time) (map[string]float64, map[string]int64, error) {
+	dataCache.Lock()
+	defer dataCache.Unlock()
+	refreshDataCache(today)
+
+	if dataCache.tracked == nil {
+		days, err := sheetDays()
+		if err != nil {
+			return nil, nil, err
+		}
+		tracked, events := map[string]float64{}, map[string]int64{}
+		if len(days) > 0 {
+			entries, err := readEntries(days[0], today)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, entry := range entries {
+				events[entry.Event]++
+				if countsTowardsTotal(entry.Event) {
+					tracked[entry.Name] += entry.Duration.Seconds()
+				}
+			}
+		}
+		dataCache.tracked, dataCache.events = tracked, events
+	}
+	return dataCache.tracked, dataCache.events, nil
+}
+
+// metricLabel escapes a label value for the text exposition format.
+func metricLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeMetrics writes the metrics in the Prometheus text format. The
+// timer gauges and write errors are only written by a process with a
+// timer, live; without one, the counters are summed from the workbook.
+func writeMetrics(w io.Writer, live *liveStatus, tracked map[string]float64, events map[string]int64) {
+	if live != nil {
+		boolValue := func(b bool) int {
+			if b {
+				return 1
+			}
+			return 0
+		}
+
+		fmt.Fprintln(w, "# HELP timer_running Whether the timer is running.")
+		fmt.Fprintln(w, "# TYPE timer_running gauge")
+		fmt.Fprintf(w, "timer_running %d\n", boolValue(live.Running))
+		fmt.Fprintln(w, "# HELP timer_paused Whether the running timer is paused.")
+		fmt.Fprintln(w, "# TYPE timer_paused gauge")
+		fmt.Fprintf(w, "timer_paused %d\n", boolValue(live.Paused))
+		fmt.Fprintln(w, "# HELP timer_current_elapsed_seconds Time tracked on the current activity.")
+		fmt.Fprintln(w, "# TYPE timer_current_elapsed_seconds gauge")
+		if live.Running {
+			fmt.Fprintf(w, "timer_current_elapsed_seconds{activity=\"%s\"} %d\n", metricLabel(live.Activity), live.Elapsed)
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP timer_tracked_seconds_total Time tracked per activity.")
+	fmt.Fprintln(w, "# TYPE timer_tracked_seconds_total counter")
+	for _, name := range sortedKeys(tracked) {
+		fmt.Fprintf(w, "timer_tracked_seconds_total{activity=\"%s\"} %g\n", metricLabel(name), tracked[name])
+	}
+	fmt.Fprintln(w, "# HELP timer_events_total Events recorded per type.")
+	fmt.Fprintln(w, "# TYPE timer_events_total counter")
+	for _, event := range sortedKeys(events) {
+		fmt.Fprintf(w, "timer_events_total{event=\"%s\"} %d\n", metricLabel(event), events[event])
+	}
+
+	if live != nil {
+		fmt.Fprintln(w, "# HELP timer_storage_write_errors_total Failed writes of events to the workbook.")
+		fmt.Fprintln(w, "# TYPE timer_storage_write_errors_total counter")
+		fmt.Fprintf(w, "timer_storage_write_errors_total %d\n", storageWriteErrors.Load())
+	}
+}
+
+func serveMetrics(w http.ResponseWriter, r *http.Request) {
+	live := liveTimer.Load()
+	var out bytes.Buffer
+	if live != nil {
+		metricsMu.Lock()
+		writeMetrics(&out, live, trackedSeconds, eventCounts)
+		metricsMu.Unlock()
+	} else {
+		now := time.Now()
+		tracked, events, err := storedCounts(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeMetrics(&out, nil, tracked, events)
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(out.Bytes())
+}
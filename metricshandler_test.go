@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordMetricsAfterUndo(t *testing.T) {
+	metricsMu.Lock()
+	trackedSeconds = map[string]float64{}
+	eventCounts = map[string]int64{}
+	undoneSeconds = map[string]float64{}
+	undoneEvents = map[string]int64{}
+	metricsMu.Unlock()
+
+	stop := TimerEntry{Event: "STOP", Name: "Code", Duration: 100 * time.Second}
+	recordMetrics(stop)
+	forgetMetrics(stop)
+	recordMetrics(TimerEntry{Event: "STOP", Name: "Code", Duration: 150 * time.Second})
+
+	if got := trackedSeconds["Code"]; got != 150 {
+		t.Errorf("tracked = %g seconds, want 150", got)
+	}
+	if got := eventCounts["STOP"]; got != 1 {
+		t.Errorf("STOP counted %d times, want 1", got)
+	}
+}
+
+func TestWriteMetrics(t *testing.T) {
+	tracked := map[string]float64{`Say "hi"`: 90}
+	events := map[string]int64{"START": 2}
+
+	var out bytes.Buffer
+	writeMetrics(&out, &liveStatus{Running: true, Activity: "Code", Elapsed: 42}, tracked, events)
+	for _, line := range []string{
+		"timer_running 1",
+		"timer_paused 0",
+		`timer_current_elapsed_seconds{activity="Code"} 42`,
+		`timer_tracked_seconds_total{activity="Say \"hi\""} 90`,
+		`timer_events_total{event="START"} 2`,
+		"timer_storage_write_errors_total ",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("missing %q in\n%s", line, out.String())
+		}
+	}
+
+	out.Reset()
+	writeMetrics(&out, nil, tracked, events)
+	if strings.Contains(out.String(), "timer_running") || strings.Contains(out.String(), "write_errors") {
+		t.Errorf("metrics without a timer have its gauges:\n%s", out.String())
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+const orgTimeLayout = "2006-01-02 Mon 15:04"
+
+// orgHeading splits an activity name into the heading text and its
+// #tags as org tags.
+func orgHeading(name string) string {
+	var words, tags []string
+	for _, word := range strings.Fields(name) {
+		if len(word) > 1 && strings.HasPrefix(word, "#") {
+			tags = append(tags, strings.ReplaceAll(word[1:], ":", "_"))
+		} else {
+			words = append(words, word)
+		}
+	}
+	heading := strings.Join(words, " ")
+	if len(tags) > 0 {
+		heading += " :" + strings.Join(tags, ":") + ":"
+	}
+	return heading
+}
+
+// orgClock formats a session as a CLOCK line. It ends after the tracked
+// time, leaving pauses out.
+func orgClock(session Session) string {
+	end := session.Start.Add(session.Duration)
+	minutes := int(session.Duration.Round(time.Minute).Minutes())
+	return fmt.Sprintf("CLOCK: [%s]--[%s] => %2d:%02d", session.Start.Format(orgTimeLayout),
+		end.Format(orgTimeLayout), minutes/60, minutes%60)
+}
+
+// writeOrg writes sessions as org headings per activity with their
+// CLOCK lines, under a heading per day or, with byProject, per project.
+func writeOrg(w io.Writer, sessions []Session, byProject bool) error {
+	type group struct {
+		title      string
+		activities []string
+		clocks     map[string][]Session
+	}
+	groups := map[string]*group{}
+	var keys []string
+	projectFor := projectLookup()
+
+	for _, session := range sessions {
+		key := session.Start.Format("2006-01-02")
+		title := session.Start.Format("2006-01-02 Mon")
+		if byProject {
+			key = projectFor(session.Name)
+			title = key
+			if title == "" {
+				title = "No project"
+			}
+		}
+
+		g, ok := groups[key]
+		if !ok {
+			g = &group{title: title, clocks: map[string][]Session{}}
+			groups[key] = g
+			keys = append(keys, key)
+		}
+		if _, ok := g.clocks[session.Name]; !ok {
+			g.activities = append(g.activities, session.Name)
+		}
+		g.clocks[session.Name] = append(g.clocks[session.Name], session)
+	}
+
+	// Days sort by date; projects by name, with no project last.
+	sort.SliceStable(keys, func(i, j int) bool {
+		if keys[i] == "" || keys[j] == "" {
+			return keys[j] == ""
+		}
+		return keys[i] < keys[j]
+	})
+
+	out := bufio.NewWriter(w)
+	for _, key := range keys {
+		g := groups[key]
+		fmt.Fprintf(out, "* %s\n", g.title)
+		for _, name := range g.activities {
+			fmt.Fprintf(out, "** %s\n", orgHeading(name))
+			fmt.Fprintln(out, "   :LOGBOOK:")
+			for _, session := range g.clocks[name] {
+				fmt.Fprintf(out, "   %s\n", orgClock(session))
+			}
+			fmt.Fprintln(out, "   :END:")
+		}
+	}
+	return out.Flush()
+}
+
+// exportOrg writes the sessions of query as org headings.
+func exportOrg(w io.Writer, query sessionQuery, byProject bool) error {
+	sessions, err := filteredSessions(query)
+	if err != nil {
+		return err
+	}
+	return writeOrg(w, sessions, byProject)
+}
+
+func exportOrgCommand(args []string) error {
+	flags := flag.NewFlagSet("export-org", flag.ContinueOnError)
+	groupBy := flags.String("group", "day", "group headings by day or project")
+	shared := sessionFilterFlags(flags, "")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *groupBy != "day" && *groupBy != "project" {
+		return fmt.Errorf("invalid group %q, want day or project", *groupBy)
+	}
+
+	query, out, err := shared.open()
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return exportOrg(out, query, *groupBy == "project")
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// timesheetColumns are the table columns of a timesheet, in mm on A4.
+var timesheetColumns = []struct {
+	title string
+	width float64
+	align string
+}{
+	{"Activity", 80, "L"},
+	{"Project", 40, "L"},
+	{"Start", 20, "C"},
+	{"End", 20, "C"},
+	{"Duration", 20, "R"},
+}
+
+// fitText shortens text with an ellipsis until it fits width.
+func fitText(pdf *fpdf.Fpdf, text string, width float64) string {
+	if pdf.GetStringWidth(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && pdf.GetStringWidth(string(runes)+"...") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+// writeTimesheet writes a printable timesheet of sessions: a table per
+// day with its total, the total of the period and a signature block.
+func writeTimesheet(w io.Writer, sessions []Session, from, to time.Time, name string) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetTitle("Timesheet", true)
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.CellFormat(0, 10, fmt.Sprintf("Page %d of {nb}", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 10, "Timesheet", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	if name != "" {
+		pdf.CellFormat(0, 6, tr("Name: "+name), "", 1, "L", false, 0, "")
+	}
+	pdf.CellFormat(0, 6, fmt.Sprintf("Period: %s to %s", from.Format("2006-01-02"), to.Format("2006-01-02")),
+		"", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	header := func() {
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.SetFillColor(230, 230, 230)
+		for _, column := range timesheetColumns {
+			pdf.CellFormat(column.width, 7, column.title, "1", 0, column.align, true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("Helvetica", "", 9)
+	}
+
+	projectFor := projectLookup()
+	_, pageHeight := pdf.GetPageSize()
+	var total time.Duration
+	for i := 0; i < len(sessions); {
+		day := sessions[i].Start.Format("2006-01-02")
+
+		// Keep a day's heading on the page of its first rows.
+		if pdf.GetY() > pageHeight-50 {
+			pdf.AddPage()
+		}
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.CellFormat(0, 8, sessions[i].Start.Format("Monday, 2 January 2006"), "", 1, "L", false, 0, "")
+		header()
+
+		var dayTotal time.Duration
+		for ; i < len(sessions) && sessions[i].Start.Format("2006-01-02") == day; i++ {
+			session := sessions[i]
+			cells := []string{
+				fitText(pdf, tr(session.Name), timesheetColumns[0].width-2),
+				fitText(pdf, tr(projectFor(session.Name)), timesheetColumns[1].width-2),
+				session.Start.Format("15:04"),
+				session.End.Format("15:04"),
+				formatDuration(session.Duration),
+			}
+			for c, column := range timesheetColumns {
+				pdf.CellFormat(column.width, 6, cells[c], "1", 0, column.align, false, 0, "")
+			}
+			pdf.Ln(-1)
+			dayTotal += session.Duration
+		}
+		total += dayTotal
+
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.CellFormat(160, 6, "Day total", "1", 0, "R", false, 0, "")
+		pdf.CellFormat(20, 6, formatDuration(dayTotal), "1", 1, "R", false, 0, "")
+		pdf.Ln(4)
+	}
+
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(160, 8, "Total", "", 0, "R", false, 0, "")
+	pdf.CellFormat(20, 8, formatDuration(total), "", 1, "R", false, 0, "")
+
+	if pdf.GetY() > pageHeight-60 {
+		pdf.AddPage()
+	}
+	pdf.Ln(16)
+	pdf.SetFont("Helvetica", "", 10)
+	for _, signer := range []string{"Employee", "Approved by"} {
+		y := pdf.GetY()
+		pdf.Line(15, y, 95, y)
+		pdf.Line(115, y, 175, y)
+		pdf.CellFormat(100, 6, signer+" signature", "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 6, "Date", "", 1, "L", false, 0, "")
+		pdf.Ln(14)
+	}
+
+	return pdf.Output(w)
+}
+
+// currentUserName is the default name on a timesheet: the account's full
+// name, else its login name.
+func currentUserName() string {
+	if account, err := user.Current(); err == nil {
+		if account.Name != "" {
+			return account.Name
+		}
+		return account.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return os.Getenv("USERNAME")
+}
+
+// exportTimesheet writes the sessions of query as a PDF timesheet.
+func exportTimesheet(w io.Writer, query sessionQuery, name string) error {
+	sessions, err := filteredSessions(query)
+	if err != nil {
+		return err
+	}
+	return writeTimesheet(w, sessions, query.From, query.To, name)
+}
+
+func timesheetCommand(args []string) error {
+	flags := flag.NewFlagSet("timesheet", flag.ContinueOnError)
+	name := flags.String("name", currentUserName(), "name printed on the timesheet")
+	shared := sessionFilterFlags(flags, "timesheet.pdf")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	query, out, err := shared.open()
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return exportTimesheet(out, query, *name)
+}
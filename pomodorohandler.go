@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	phaseWork       = "POMODORO"
+	phaseShortBreak = "SHORT_BREAK"
+	phaseLongBreak  = "LONG_BREAK"
+)
+
+var (
+	PomodoroWork       = 25 * time.Minute
+	PomodoroShortBreak = 5 * time.Minute
+	PomodoroLongBreak  = 15 * time.Minute
+	PomodoroLongEvery  = 4
+)
+
+type PomodoroState struct {
+	enabled      bool
+	phase        string
+	phaseElapsed time.Duration
+	completed    int
+	day          string
+}
+
+var (
+	pomodoro      PomodoroState
+	pomodoroLabel *widget.Label
+)
+
+func phaseLength(phase string) time.Duration {
+	switch phase {
+	case phaseShortBreak:
+		return PomodoroShortBreak
+	case phaseLongBreak:
+		return PomodoroLongBreak
+	default:
+		return PomodoroWork
+	}
+}
+
+func setPomodoro(enabled bool) {
+	pomodoro.enabled = enabled
+	resetPomodoro()
+	if !enabled && state.running {
+		timeLabel.SetText(formatDuration(Elapsed))
+	}
+}
+
+// resetPomodoro starts over with a work phase. It is called whenever the
+// timer is started or stopped.
+func resetPomodoro() {
+	pomodoro.phase = phaseWork
+	pomodoro.phaseElapsed = 0
+	refreshPomodoroCount()
+}
+
+// refreshPomodoroCount loads today's count from the summary sheet when
+// the day changes.
+func refreshPomodoroCount() {
+	today := time.Now().Format("2006-01-02")
+	if pomodoro.day != today {
+		pomodoro.day = today
+		pomodoro.completed = loadPomodoroCount(today)
+	}
+	if pomodoroLabel != nil {
+		pomodoroLabel.SetText(fmt.Sprintf("Pomodoros today: %d", pomodoro.completed))
+	}
+}
+
+// tickPomodoro is called from the update loop. It counts down the
+// current phase in timeLabel and moves on to the next phase when it
+// runs out. Breaks pause the activity timer; resuming during a break
+// ends the break early.
+func tickPomodoro() {
+	if !pomodoro.enabled || !state.running {
+		return
+	}
+
+	if pomodoro.phase == phaseWork {
+		if state.paused {
+			return
+		}
+	} else if !state.paused {
+		finishPhase()
+		return
+	}
+
+	pomodoro.phaseElapsed += WaitDuration
+	remaining := phaseLength(pomodoro.phase) - pomodoro.phaseElapsed
+	if remaining <= 0 {
+		finishPhase()
+		return
+	}
+	timeLabel.SetText(formatDuration(remaining))
+}
+
+// finishPhase logs the phase that just ended and switches to the next.
+// Breaks pause the timer and work resumes it through togglePause, so the
+// PAUSE and RESUME rows, hooks and undo work as for a manual pause.
+func finishPhase() {
+	recordEntry(TimerEntry{
+		Timestamp: time.Now(),
+		Event:     pomodoro.phase,
+		Name:      nameEntry.Text,
+		Duration:  pomodoro.phaseElapsed,
+	})
+
+	if pomodoro.phase == phaseWork {
+		refreshPomodoroCount()
+		pomodoro.completed++
+		savePomodoroCount(pomodoro.day, pomodoro.completed)
+		refreshPomodoroCount()
+
+		pomodoro.phase = phaseShortBreak
+		if PomodoroLongEvery > 0 && pomodoro.completed%PomodoroLongEvery == 0 {
+			pomodoro.phase = phaseLongBreak
+		}
+	} else {
+		pomodoro.phase = phaseWork
+	}
+
+	pomodoro.phaseElapsed = 0
+	if state.paused == (pomodoro.phase == phaseWork) {
+		togglePause()
+	}
+	timeLabel.SetText(formatDuration(phaseLength(pomodoro.phase)))
+}
+
+// showPomodoroSettings lets the phase lengths be changed in minutes.
+func showPomodoroSettings() {
+	w := windowMaker(App, "Pomodoro")
+
+	workEntry := widget.NewEntry()
+	workEntry.SetText(strconv.Itoa(int(PomodoroWork.Minutes())))
+	shortEntry := widget.NewEntry()
+	shortEntry.SetText(strconv.Itoa(int(PomodoroShortBreak.Minutes())))
+	longEntry := widget.NewEntry()
+	longEntry.SetText(strconv.Itoa(int(PomodoroLongBreak.Minutes())))
+	everyEntry := widget.NewEntry()
+	everyEntry.SetText(strconv.Itoa(PomodoroLongEvery))
+
+	saveButton := button("Save", func() {
+		work, err1 := strconv.Atoi(workEntry.Text)
+		short, err2 := strconv.Atoi(shortEntry.Text)
+		long, err3 := strconv.Atoi(longEntry.Text)
+		every, err4 := strconv.Atoi(everyEntry.Text)
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil || work <= 0 || short <= 0 || long <= 0 {
+			slog.Warn("Invalid pomodoro lengths")
+			return
+		}
+
+		PomodoroWork = time.Duration(work) * time.Minute
+		PomodoroShortBreak = time.Duration(short) * time.Minute
+		PomodoroLongBreak = time.Duration(long) * time.Minute
+		PomodoroLongEvery = every
+		persistConfig()
+		w.Close()
+	})
+
+	w.SetContent(container.NewVBox(
+		widget.NewForm(
+			widget.NewFormItem("Work (min)", workEntry),
+			widget.NewFormItem("Short break (min)", shortEntry),
+			widget.NewFormItem("Long break (min)", longEntry),
+			widget.NewFormItem("Long break every", everyEntry),
+		),
+		saveButton,
+	))
+	w.Show()
+}
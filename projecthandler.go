@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// ProjectRule puts activities whose name matches the regular expression
+// Match into Project. Matching ignores case.
+type ProjectRule struct {
+	Match   string `toml:"match"`
+	Project string `toml:"project"`
+
+	re *regexp.Regexp
+}
+
+// ProjectRules are tried in order; the first match wins.
+var ProjectRules []ProjectRule
+
+// compileProjectRules returns a copy of rules with their expressions
+// compiled. Rules that do not compile are kept, so saving the config
+// does not lose them, but never match; their errors are returned.
+func compileProjectRules(rules []ProjectRule) ([]ProjectRule, error) {
+	compiled := make([]ProjectRule, len(rules))
+	var errs []error
+	for i, rule := range rules {
+		re, err := regexp.Compile("(?i)" + rule.Match)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("project rule %q: %w", rule.Match, err))
+		}
+		rule.re = re
+		compiled[i] = rule
+	}
+	return compiled, errors.Join(errs...)
+}
+
+// ruleProject returns the project of the first rule matching name.
+func ruleProject(name string) string {
+	for _, rule := range ProjectRules {
+		if rule.re != nil && rule.re.MatchString(name) {
+			return rule.Project
+		}
+	}
+	return ""
+}
+
+// projectOf returns the project set on the activity in the workbook, or
+// else the one its name matches in ProjectRules.
+func projectOf(name string) string {
+	if activity, ok := loadActivity(name); ok && activity.Project != "" {
+		return activity.Project
+	}
+	return ruleProject(name)
+}
+
+// projectLookup reads the activities of the workbook once and returns a
+// function giving the project of a name as projectOf would. Exports use
+// it so the workbook is not reopened for every session.
+func projectLookup() func(name string) string {
+	activities := loadActivities()
+	return func(name string) string {
+		if project := activities[name].Project; project != "" {
+			return project
+		}
+		return ruleProject(name)
+	}
+}
+
+// assignProjects records the project the rules give each activity that
+// has none yet, so it stays put when the rules change. The activities
+// are saved together.
+func assignProjects(names []string) error {
+	known := loadActivities()
+	var changed []Activity
+	for _, name := range names {
+		project := ruleProject(name)
+		activity, ok := known[name]
+		if project == "" || (ok && activity.Project != "") {
+			continue
+		}
+		activity.Name = name
+		activity.Project = project
+		known[name] = activity
+		changed = append(changed, activity)
+	}
+	return saveActivities(changed)
+}
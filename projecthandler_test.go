@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestRuleProject(t *testing.T) {
+	old := ProjectRules
+	t.Cleanup(func() { ProjectRules = old })
+
+	rules, err := compileProjectRules([]ProjectRule{
+		{Match: "cust(omer", Project: "Broken"},
+		{Match: "^acme", Project: "Acme"},
+	})
+	if err == nil {
+		t.Error("an invalid rule compiled without an error")
+	}
+	if len(rules) != 2 {
+		t.Fatalf("got %d rules, want the invalid one kept", len(rules))
+	}
+	ProjectRules = rules
+
+	if got := ruleProject("ACME site"); got != "Acme" {
+		t.Errorf("ruleProject = %q, want Acme", got)
+	}
+	if got := ruleProject("customer call"); got != "" {
+		t.Errorf("ruleProject = %q from an invalid rule", got)
+	}
+}
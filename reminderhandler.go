@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// ReminderRules decides when desktop notifications are sent. A zero
+// duration turns its rule off.
+type ReminderRules struct {
+	Enabled          bool          `toml:"enabled"`
+	LongRunning      time.Duration `toml:"long_running"`
+	BreakEvery       time.Duration `toml:"break_every"`
+	NoTimerAfter     time.Duration `toml:"no_timer_after"`
+	WorkStart        int           `toml:"work_start"`
+	WorkEnd          int           `toml:"work_end"`
+	BudgetThresholds []int         `toml:"budget_thresholds"`
+}
+
+var Reminders = ReminderRules{
+	Enabled:          true,
+	LongRunning:      3 * time.Hour,
+	BreakEvery:       time.Hour,
+	NoTimerAfter:     15 * time.Minute,
+	WorkStart:        9,
+	WorkEnd:          17,
+	BudgetThresholds: []int{80, 100},
+}
+
+var ReminderRate = 30 * time.Second
+
+var (
+	longRunningSent bool
+	workingSince    = time.Now()
+	stoppedSince    = time.Now()
+	thresholdsSent  = map[string]bool{}
+)
+
+// runReminders evaluates the reminder rules until the app exits. It runs
+// next to updateTimeDisplay.
+func runReminders() {
+	defer Wg.Done()
+	for {
+		checkReminders(time.Now())
+		if !sleepOrDone(ReminderRate) {
+			return
+		}
+	}
+}
+
+func notify(title string, content string) {
+	App.SendNotification(fyne.NewNotification(title, content))
+}
+
+func checkReminders(now time.Time) {
+	if !state.running {
+		longRunningSent = false
+		workingSince = now
+		clear(thresholdsSent)
+	} else {
+		stoppedSince = now
+	}
+	if state.paused {
+		workingSince = now
+	}
+
+	if !Reminders.Enabled {
+		return
+	}
+
+	if Reminders.LongRunning > 0 && state.running && !longRunningSent &&
+		now.Sub(state.startTime) >= Reminders.LongRunning {
+		longRunningSent = true
+		notify("Timer still running", fmt.Sprintf("%q has been running for %s.",
+			nameEntry.Text, formatDuration(now.Sub(state.startTime))))
+	}
+
+	if Reminders.BreakEvery > 0 && state.running && !state.paused &&
+		now.Sub(workingSince) >= Reminders.BreakEvery {
+		workingSince = now
+		notify("Time for a break", fmt.Sprintf("You have been working for %s without a pause.",
+			formatDuration(Reminders.BreakEvery)))
+	}
+
+	if Reminders.NoTimerAfter > 0 && !state.running && inWorkingHours(now) &&
+		now.Sub(stoppedSince) >= Reminders.NoTimerAfter {
+		stoppedSince = now
+		notify("No timer running", "It is working hours and nothing is being tracked.")
+	}
+
+	for _, l := range budgetLimits() {
+		if !state.running {
+			break
+		}
+		for _, threshold := range Reminders.BudgetThresholds {
+			key := fmt.Sprint(l.name, threshold)
+			if thresholdsSent[key] || l.used*100 < l.limit*time.Duration(threshold) {
+				continue
+			}
+			thresholdsSent[key] = true
+			notify("Budget", fmt.Sprintf("%d%% of %s used for %q.", threshold, l.name, activeActivity.Name))
+		}
+	}
+}
+
+// inWorkingHours reports whether now is a weekday between WorkStart and
+// WorkEnd o'clock.
+func inWorkingHours(now time.Time) bool {
+	if now.Weekday() == time.Saturday || now.Weekday() == time.Sunday {
+		return false
+	}
+	return now.Hour() >= Reminders.WorkStart && now.Hour() < Reminders.WorkEnd
+}
+
+// formatThresholds and parseThresholds convert budget thresholds to and
+// from a comma separated list of percentages.
+func formatThresholds(thresholds []int) string {
+	parts := make([]string, len(thresholds))
+	for i, t := range thresholds {
+		parts[i] = strconv.Itoa(t)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func parseThresholds(text string) ([]int, error) {
+	var thresholds []int
+	for _, part := range strings.Split(text, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		t, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, err
+		}
+		thresholds = append(thresholds, t)
+	}
+	return thresholds, nil
+}
+
+// showReminderSettings edits the reminder rules.
+func showReminderSettings() {
+	w := windowMaker(App, "Reminders")
+
+	enabledCheck := widget.NewCheck("Send notifications", nil)
+	enabledCheck.SetChecked(Reminders.Enabled)
+	longEntry := widget.NewEntry()
+	longEntry.SetText(strconv.Itoa(int(Reminders.LongRunning.Minutes())))
+	breakEntry := widget.NewEntry()
+	breakEntry.SetText(strconv.Itoa(int(Reminders.BreakEvery.Minutes())))
+	noTimerEntry := widget.NewEntry()
+	noTimerEntry.SetText(strconv.Itoa(int(Reminders.NoTimerAfter.Minutes())))
+	startEntry := widget.NewEntry()
+	startEntry.SetText(strconv.Itoa(Reminders.WorkStart))
+	endEntry := widget.NewEntry()
+	endEntry.SetText(strconv.Itoa(Reminders.WorkEnd))
+	thresholdEntry := widget.NewEntry()
+	thresholdEntry.SetText(formatThresholds(Reminders.BudgetThresholds))
+
+	saveButton := button("Save", func() {
+		long, err1 := strconv.Atoi(longEntry.Text)
+		breakEvery, err2 := strconv.Atoi(breakEntry.Text)
+		noTimer, err3 := strconv.Atoi(noTimerEntry.Text)
+		start, err4 := strconv.Atoi(startEntry.Text)
+		end, err5 := strconv.Atoi(endEntry.Text)
+		thresholds, err6 := parseThresholds(thresholdEntry.Text)
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil || err5 != nil || err6 != nil {
+			slog.Warn("Invalid reminder settings")
+			return
+		}
+
+		Reminders = ReminderRules{
+			Enabled:          enabledCheck.Checked,
+			LongRunning:      time.Duration(long) * time.Minute,
+			BreakEvery:       time.Duration(breakEvery) * time.Minute,
+			NoTimerAfter:     time.Duration(noTimer) * time.Minute,
+			WorkStart:        start,
+			WorkEnd:          end,
+			BudgetThresholds: thresholds,
+		}
+		persistConfig()
+		w.Close()
+	})
+
+	w.SetContent(container.NewVBox(
+		enabledCheck,
+		widget.NewForm(
+			widget.NewFormItem("Running longer than (min)", longEntry),
+			widget.NewFormItem("Break every (min)", breakEntry),
+			widget.NewFormItem("No timer for (min)", noTimerEntry),
+			widget.NewFormItem("Work starts (hour)", startEntry),
+			widget.NewFormItem("Work ends (hour)", endEntry),
+			widget.NewFormItem("Budget thresholds (%)", thresholdEntry),
+		),
+		saveButton,
+	))
+	w.Show()
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ServerConfig serves the dashboard and, with Metrics, a Prometheus
+// /metrics endpoint on a local address while the app runs. "timer serve"
+// serves them without the app.
+type ServerConfig struct {
+	Enabled bool   `toml:"enabled"`
+	Address string `toml:"address"`
+	Metrics bool   `toml:"metrics"`
+}
+
+var (
+	Server = ServerConfig{
+		Address: "127.0.0.1:8377",
+	}
+
+	httpServer *http.Server
+	liveTimer  atomic.Pointer[liveStatus]
+
+	// dataCache keeps what was read from the workbook until it changes
+	// or the day turns, so clients polling the server do not reread it.
+	dataCache struct {
+		sync.Mutex
+		modTime time.Time
+		day     string
+		recent  []TimerEntry
+		summary *dashboardSummary
+		tracked map[string]float64
+		events  map[string]int64
+	}
+)
+
+//go:embed dashboard.html
+var dashboardHTML []byte
+
+// liveStatus is the running timer as the dashboard sees it.
+type liveStatus struct {
+	Running  bool   `json:"running"`
+	Paused   bool   `json:"paused"`
+	Activity string `json:"activity"`
+	Since    string `json:"since,omitempty"`
+	Elapsed  int64  `json:"elapsed"`
+}
+
+// tickServer publishes the timer state for the dashboard. It runs in the
+// update loop, so the handlers never touch the timer themselves.
+func tickServer() {
+	status := &liveStatus{Running: state.running, Paused: state.paused}
+	if state.running {
+		status.Activity = nameEntry.Text
+		status.Since = state.startTime.Format(time.RFC3339)
+		status.Elapsed = int64(Elapsed.Seconds())
+	}
+	liveTimer.Store(status)
+}
+
+// currentLiveStatus is the state of the app's timer, or under "timer
+// serve", which has none, the state the workbook was last left in. A
+// timer started before yesterday is not found there.
+func currentLiveStatus() *liveStatus {
+	if status := liveTimer.Load(); status != nil {
+		return status
+	}
+	now := time.Now()
+	recent, err := recentEntries(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local))
+	if err != nil {
+		return &liveStatus{}
+	}
+	return storedStatus(recent, now)
+}
+
+// storedStatus replays the START, PAUSE, RESUME and STOP rows of entries
+// to tell whether the timer is running and for how long it has counted.
+func storedStatus(entries []TimerEntry, now time.Time) *liveStatus {
+	sorted := append([]TimerEntry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	status := &liveStatus{}
+	var counted time.Duration
+	var resumed time.Time
+	for _, entry := range sorted {
+		switch entry.Event {
+		case "START":
+			status = &liveStatus{Running: true, Activity: entry.Name, Since: entry.Timestamp.Format(time.RFC3339)}
+			counted, resumed = 0, entry.Timestamp
+		case "PAUSE":
+			if status.Running && !status.Paused {
+				counted += entry.Timestamp.Sub(resumed)
+				status.Paused = true
+			}
+		case "RESUME":
+			if status.Running && status.Paused {
+				resumed = entry.Timestamp
+				status.Paused = false
+			}
+		case "IDLE", "REASSIGN":
+			if status.Running {
+				counted -= entry.Duration
+			}
+		case "STOP", "EXIT":
+			status = &liveStatus{}
+		}
+	}
+	if status.Running && !status.Paused {
+		counted += now.Sub(resumed)
+	}
+	if status.Running {
+		status.Elapsed = int64(max(counted, 0).Seconds())
+	}
+	return status
+}
+
+// refreshDataCache empties dataCache when the workbook or the day has
+// changed since it was filled. dataCache must be locked.
+func refreshDataCache(today time.Time) {
+	modTime := dataModTime()
+	day := today.Format("2006-01-02")
+	if !dataCache.modTime.Equal(modTime) || dataCache.day != day {
+		dataCache.modTime = modTime
+		dataCache.day = day
+		dataCache.recent = nil
+		dataCache.summary = nil
+		dataCache.tracked = nil
+		dataCache.events = nil
+	}
+}
+
+// recentEntries returns the rows of yesterday and today.
+func recentEntries(today time.Time) ([]TimerEntry, error) {
+	dataCache.Lock()
+	defer dataCache.Unlock()
+	refreshDataCache(today)
+
+	if dataCache.recent == nil {
+		entries, err := readEntries(today.AddDate(0, 0, -1), today)
+		if err != nil {
+			return nil, err
+		}
+		dataCache.recent = append([]TimerEntry{}, entries...)
+	}
+	return dataCache.recent, nil
+}
+
+// cachedSummary returns the dashboard summary of today.
+func cachedSummary(now time.Time) (dashboardSummary, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	dataCache.Lock()
+	defer dataCache.Unlock()
+	refreshDataCache(today)
+
+	if dataCache.summary == nil {
+		summary, err := buildSummary(now)
+		if err != nil {
+			return dashboardSummary{}, err
+		}
+		dataCache.summary = &summary
+	}
+	return *dataCache.summary, nil
+}
+
+// activityTotal is the time tracked on an activity or a day.
+type activityTotal struct {
+	Name    string `json:"name"`
+	Project string `json:"project,omitempty"`
+	Seconds int64  `json:"seconds"`
+}
+
+// dashboardSummary is what the dashboard draws.
+type dashboardSummary struct {
+	Today  []sessionPayload `json:"today"`
+	Week   []activityTotal  `json:"week"`
+	Days   []activityTotal  `json:"days"`
+	Streak int              `json:"streak"`
+	Live   *liveStatus      `json:"live"`
+}
+
+// dashboardDays is how far back the daily chart looks. The streak reads
+// further back, streakDays at a time, only while it lasts.
+const (
+	dashboardDays = 14
+	streakDays    = 30
+)
+
+// buildSummary reads today's timeline, this week's totals per activity,
+// the last two weeks per day and the streak of days with tracked time.
+// The live status is left for the caller to fill in.
+func buildSummary(now time.Time) (dashboardSummary, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	oldest := today.AddDate(0, 0, -(dashboardDays - 1))
+	sessions, err := readSessions(oldest, today)
+	if err != nil {
+		return dashboardSummary{}, err
+	}
+
+	summary := dashboardSummary{Today: []sessionPayload{}, Week: []activityTotal{}}
+	week := weekStart(now)
+	weekTotals := map[string]time.Duration{}
+	dayTotals := map[string]time.Duration{}
+	for _, session := range sessions {
+		day := session.Start.Format("2006-01-02")
+		dayTotals[day] += session.Duration
+		if !session.Start.Before(today) {
+			summary.Today = append(summary.Today, newSessionPayload(session))
+		}
+		if !session.Start.Before(week) {
+			weekTotals[session.Name] += session.Duration
+		}
+	}
+
+	for name, total := range weekTotals {
+		summary.Week = append(summary.Week, activityTotal{Name: name, Project: projectOf(name), Seconds: int64(total.Seconds())})
+	}
+	sort.Slice(summary.Week, func(i, j int) bool {
+		return summary.Week[i].Seconds > summary.Week[j].Seconds
+	})
+
+	for i := dashboardDays - 1; i >= 0; i-- {
+		day := today.AddDate(0, 0, -i).Format("2006-01-02")
+		summary.Days = append(summary.Days, activityTotal{Name: day, Seconds: int64(dayTotals[day].Seconds())})
+	}
+
+	// A streak still counts while today has nothing tracked yet.
+	day := today
+	if dayTotals[day.Format("2006-01-02")] == 0 {
+		day = day.AddDate(0, 0, -1)
+	}
+	for {
+		for !day.Before(oldest) && dayTotals[day.Format("2006-01-02")] > 0 {
+			summary.Streak++
+			day = day.AddDate(0, 0, -1)
+		}
+		if !day.Before(oldest) {
+			break
+		}
+
+		// The streak goes back further than what has been read.
+		older, err := readSessions(oldest.AddDate(0, 0, -streakDays), oldest.AddDate(0, 0, -1))
+		if err != nil {
+			return dashboardSummary{}, err
+		}
+		oldest = oldest.AddDate(0, 0, -streakDays)
+		for _, session := range older {
+			dayTotals[session.Start.Format("2006-01-02")] += session.Duration
+		}
+	}
+	return summary, nil
+}
+
+// dataModTime returns when the workbook last changed.
+func dataModTime() time.Time {
+	info, err := os.Stat(dataFilePath())
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// serveEvents streams the timer state every second and a changed event
+// whenever the workbook is written.
+func serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	modTime := dataModTime()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		data, _ := json.Marshal(currentLiveStatus())
+		fmt.Fprintf(w, "event: state\ndata: %s\n\n", data)
+		if changed := dataModTime(); !changed.Equal(modTime) {
+			modTime = changed
+			fmt.Fprint(w, "event: changed\ndata: {}\n\n")
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func serveSummary(w http.ResponseWriter, r *http.Request) {
+	summary, err := cachedSummary(time.Now())
+	if err != nil {
+		slog.Error("Error reading dashboard data", "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	summary.Live = currentLiveStatus()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
+
+// serverHandler routes the dashboard and its data, and the metrics when
+// metrics is set.
+func serverHandler(dashboard, metrics bool) *http.ServeMux {
+	mux := http.NewServeMux()
+	if dashboard {
+		mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write(dashboardHTML)
+		})
+		mux.HandleFunc("GET /api/summary", serveSummary)
+		mux.HandleFunc("GET /events", serveEvents)
+	}
+	if metrics {
+		mux.HandleFunc("GET /metrics", serveMetrics)
+	}
+	return mux
+}
+
+// startServer serves the dashboard and metrics in the background when
+// either is enabled.
+func startServer() {
+	if !Server.Enabled && !Server.Metrics {
+		return
+	}
+	httpServer = &http.Server{Addr: Server.Address, Handler: serverHandler(Server.Enabled, Server.Metrics)}
+	go func() {
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Error serving", "err", err)
+		}
+	}()
+	slog.Info("Serving on http://" + Server.Address)
+}
+
+// stopServer closes the server and its open event streams.
+func stopServer() {
+	if httpServer != nil {
+		httpServer.Close()
+	}
+}
+
+func serveCommand(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	address := flags.String("addr", Server.Address, "address to serve the dashboard on")
+	metrics := flags.Bool("metrics", Server.Metrics, "also serve Prometheus metrics on /metrics")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Addr: *address, Handler: serverHandler(true, *metrics)}
+	go func() {
+		<-ctx.Done()
+		close(done)
+		server.Close()
+	}()
+
+	fmt.Printf("Dashboard at http://%s\n", *address)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStoredStatus(t *testing.T) {
+	at := func(minute int) time.Time {
+		return time.Date(2024, 3, 4, 9, minute, 0, 0, time.Local)
+	}
+	entries := []TimerEntry{
+		{Timestamp: at(0), Event: "START", Name: "Old"},
+		{Timestamp: at(5), Event: "STOP", Name: "Old", Duration: 5 * time.Minute},
+		{Timestamp: at(10), Event: "START", Name: "Code"},
+		{Timestamp: at(20), Event: "PAUSE", Name: "Code"},
+		{Timestamp: at(30), Event: "RESUME", Name: "Code"},
+		{Timestamp: at(40), Event: "IDLE", Name: "Code", Duration: 5 * time.Minute},
+	}
+
+	status := storedStatus(entries, at(50))
+	if !status.Running || status.Paused || status.Activity != "Code" {
+		t.Fatalf("status = %+v, want Code running", status)
+	}
+	// 10 minutes before the pause, 20 after it, less 5 idle.
+	if want := int64((25 * time.Minute).Seconds()); status.Elapsed != want {
+		t.Errorf("elapsed = %d, want %d", status.Elapsed, want)
+	}
+
+	paused := storedStatus(entries[:4], at(50))
+	if !paused.Paused || paused.Elapsed != int64((10*time.Minute).Seconds()) {
+		t.Errorf("paused status = %+v", paused)
+	}
+
+	stopped := storedStatus(append(entries, TimerEntry{Timestamp: at(45), Event: "STOP", Name: "Code"}), at(50))
+	if stopped.Running {
+		t.Errorf("status after STOP = %+v, want not running", stopped)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// Session is one stretch of work on an activity, from START to STOP.
+type Session struct {
+	Name     string
+	Start    time.Time
+	End      time.Time
+	Duration time.Duration
+	Notes    string
+}
+
+// buildSessions pairs START rows with the STOP or EXIT row that ends
+// them. The duration and notes are the ones recorded at STOP, so pauses
+// and trimmed idle time are left out. REASSIGN rows become sessions of
+// their own that end when they were recorded.
+func buildSessions(entries []TimerEntry) []Session {
+	sorted := append([]TimerEntry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	var sessions []Session
+	var open *Session
+	for _, entry := range sorted {
+		switch entry.Event {
+		case "START":
+			open = &Session{Name: entry.Name, Start: entry.Timestamp}
+		case "STOP", "EXIT":
+			if open == nil {
+				continue
+			}
+			open.End = entry.Timestamp
+			open.Duration = entry.Duration
+			open.Notes = entry.Notes
+			if open.Duration <= 0 {
+				open.Duration = open.End.Sub(open.Start)
+			}
+			sessions = append(sessions, *open)
+			open = nil
+		case "REASSIGN":
+			sessions = append(sessions, Session{
+				Name:     entry.Name,
+				Start:    entry.Timestamp.Add(-entry.Duration),
+				End:      entry.Timestamp,
+				Duration: entry.Duration,
+				Notes:    entry.Notes,
+			})
+		}
+	}
+	return sessions
+}
+
+// readSessions returns the finished sessions that started between the
+// dates of from and to.
+func readSessions(from, to time.Time) ([]Session, error) {
+	entries, err := readEntries(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return buildSessions(entries), nil
+}
+
+// sessionQuery is the days an export covers and the filter its sessions
+// must match.
+type sessionQuery struct {
+	From   time.Time
+	To     time.Time
+	Filter exportFilter
+}
+
+// filteredSessions returns the finished sessions of the query.
+func filteredSessions(query sessionQuery) ([]Session, error) {
+	sessions, err := readSessions(query.From, query.To)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []Session
+	for _, session := range sessions {
+		if query.Filter.match(session.Name) {
+			matched = append(matched, session)
+		}
+	}
+	return matched, nil
+}
+
+// sessionEntries turns a session into the START and STOP rows the timer
+// would have written for it.
+func sessionEntries(session Session) []TimerEntry {
+	duration := session.Duration
+	if duration <= 0 {
+		duration = session.End.Sub(session.Start)
+	}
+	return []TimerEntry{
+		{Timestamp: session.Start, Event: "START", Name: session.Name},
+		{Timestamp: session.End, Event: "STOP", Name: session.Name, Duration: duration, Notes: session.Notes},
+	}
+}
+
+// importedSession is a session read from another tracker, with the
+// project and client it was filed under there.
+type importedSession struct {
+	Session
+	Project string
+	Client  string
+}
+
+// importSessions writes sessions to the workbook and records their
+// project and client on activities that have none yet.
+func importSessions(sessions []importedSession) (added, skipped int, err error) {
+	var entries []TimerEntry
+	for _, session := range sessions {
+		entries = append(entries, sessionEntries(session.Session)...)
+	}
+	added, skipped, err = appendEntries(entries)
+	if err != nil {
+		return added, skipped, err
+	}
+
+	known := loadActivities()
+	var changed []Activity
+	for _, session := range sessions {
+		activity, ok := known[session.Name]
+		if (session.Project == "" && session.Client == "") || (ok && (activity.Project != "" || activity.Client != "")) {
+			continue
+		}
+		activity.Name = session.Name
+		activity.Project = session.Project
+		activity.Client = session.Client
+		known[session.Name] = activity
+		changed = append(changed, activity)
+	}
+	return added, skipped, saveActivities(changed)
+}
+
+// previewSessions prints sessions as they would be imported.
+func previewSessions(out io.Writer, sessions []importedSession) {
+	for _, session := range sessions {
+		fmt.Fprintf(out, "%s\t%s\t%s\t%s\t%s\t%s\n", session.Start.Format("2006-01-02 15:04"),
+			session.End.Format("15:04"), formatDuration(session.Duration), session.Name, session.Project, session.Client)
+	}
+	fmt.Fprintf(out, "%d sessions would be imported\n", len(sessions))
+}
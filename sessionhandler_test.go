@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildSessionsReassign(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 3, 4, hour, minute, 0, 0, time.Local)
+	}
+	entries := []TimerEntry{
+		{Timestamp: at(9, 0), Event: "START", Name: "Code"},
+		{Timestamp: at(10, 30), Event: "REASSIGN", Name: "Meeting", Duration: 30 * time.Minute},
+		{Timestamp: at(11, 0), Event: "STOP", Name: "Code", Duration: 90 * time.Minute},
+	}
+
+	sessions := buildSessions(entries)
+	if len(sessions) != 2 {
+		t.Fatalf("got %d sessions, want 2: %+v", len(sessions), sessions)
+	}
+
+	meeting := sessions[0]
+	if meeting.Name != "Meeting" || !meeting.Start.Equal(at(10, 0)) || !meeting.End.Equal(at(10, 30)) ||
+		meeting.Duration != 30*time.Minute {
+		t.Errorf("reassigned session = %+v", meeting)
+	}
+	code := sessions[1]
+	if code.Name != "Code" || !code.Start.Equal(at(9, 0)) || code.Duration != 90*time.Minute {
+		t.Errorf("running session = %+v", code)
+	}
+}
+
+func TestBuildSessions(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 3, 4, hour, minute, 0, 0, time.Local)
+	}
+	// Out of order, with a STOP that has no START and an EXIT with no
+	// recorded duration.
+	entries := []TimerEntry{
+		{Timestamp: at(12, 0), Event: "START", Name: "Docs"},
+		{Timestamp: at(8, 0), Event: "STOP", Name: "Stray", Duration: time.Hour},
+		{Timestamp: at(9, 0), Event: "START", Name: "Code"},
+		{Timestamp: at(9, 30), Event: "PAUSE", Name: "Code"},
+		{Timestamp: at(10, 0), Event: "RESUME", Name: "Code"},
+		{Timestamp: at(11, 0), Event: "STOP", Name: "Code", Duration: 90 * time.Minute, Notes: "Done"},
+		{Timestamp: at(12, 45), Event: "EXIT", Name: "Docs"},
+	}
+
+	sessions := buildSessions(entries)
+	if len(sessions) != 2 {
+		t.Fatalf("got %d sessions, want 2: %+v", len(sessions), sessions)
+	}
+	if code := sessions[0]; code.Name != "Code" || code.Duration != 90*time.Minute || code.Notes != "Done" {
+		t.Errorf("first session = %+v", code)
+	}
+	if docs := sessions[1]; docs.Name != "Docs" || docs.Duration != 45*time.Minute {
+		t.Errorf("EXIT session = %+v, want the time from START", docs)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	ExitHookDeadline = 10 * time.Second
+	ExitSyncDeadline = 5 * time.Second
+
+	done         = make(chan struct{})
+	shutdownOnce sync.Once
+)
+
+// handleSignals shuts down the same way as closing the window on SIGINT
+// and SIGTERM.
+func handleSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		shutdown()
+	}()
+}
+
+// shutdown quits in order: stop the update loops and the dashboard,
+// record EXIT, sync the workbook, try the queued webhooks and wait for
+// the exit hooks, both within ExitHookDeadline, then quit. Every write
+// to the workbook is saved before it returns, so there is nothing to
+// flush. It only runs once and does not block the caller.
+func shutdown() {
+	shutdownOnce.Do(func() {
+		slog.Info("Saving to Excel...")
+		go func() {
+			close(done)
+			Wg.Wait()
+			stopServer()
+
+			logEvent("EXIT")
+			syncOnExit()
+			deadline := time.Now().Add(ExitHookDeadline)
+			flushWebhooks(deadline)
+			if !waitHooks(deadline) {
+				slog.Warn("Exit hooks still running, quitting anyway")
+			}
+			App.Quit()
+		}()
+	})
+}
+
+// sleepOrDone waits for d and reports whether the app is still running.
+func sleepOrDone(d time.Duration) bool {
+	select {
+	case <-done:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SyncConfig commits the workbook to a local git repository on exit.
+type SyncConfig struct {
+	Enabled    bool          `toml:"enabled"`
+	Repository string        `toml:"repository"`
+	Push       bool          `toml:"push"`
+	Remote     string        `toml:"remote"`
+	Branch     string        `toml:"branch"`
+	Timeout    time.Duration `toml:"timeout"`
+}
+
+var Sync = SyncConfig{
+	Remote:  "origin",
+	Timeout: 30 * time.Second,
+}
+
+// syncRepository returns the configured repository, or the data
+// directory when none is set.
+func syncRepository() string {
+	if Sync.Repository != "" {
+		return Sync.Repository
+	}
+	return dataDir()
+}
+
+// git runs a git command in the sync repository and returns its output.
+func git(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", syncRepository()}, args...)...)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := cmd.Run()
+	if err != nil {
+		return output.String(), fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(output.String()))
+	}
+	return output.String(), nil
+}
+
+// syncDataFile commits the workbook with a timestamped message. The
+// workbook must be inside the repository; copying it in could overwrite
+// a tracked file of the same name.
+func syncDataFile(ctx context.Context) error {
+	repo, err := filepath.Abs(syncRepository())
+	if err != nil {
+		return err
+	}
+	file, err := filepath.Abs(dataFilePath())
+	if err != nil {
+		return err
+	}
+
+	rel, err := filepath.Rel(repo, file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("workbook %s is not inside the repository %s", file, repo)
+	}
+
+	if _, err := git(ctx, "add", "--", rel); err != nil {
+		return deadlineError(ctx, err)
+	}
+	if _, err := git(ctx, "diff", "--cached", "--quiet", "--", rel); err == nil {
+		return nil // Nothing changed
+	} else if ctx.Err() != nil {
+		return deadlineError(ctx, err)
+	}
+
+	message := time.Now().Format("2006-01-02 15:04:05")
+	if _, err := git(ctx, "commit", "-m", message, "--", rel); err != nil {
+		return deadlineError(ctx, err)
+	}
+	return nil
+}
+
+// pushRepository pushes the repository to Sync.Remote.
+func pushRepository(ctx context.Context) error {
+	args := []string{"push", Sync.Remote}
+	if Sync.Branch != "" {
+		args = append(args, Sync.Branch)
+	}
+	_, err := git(ctx, args...)
+	return deadlineError(ctx, err)
+}
+
+// deadlineError reports err as a timeout when ctx ran out. A killed git
+// reports its signal, not the deadline.
+func deadlineError(ctx context.Context, err error) error {
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("git timed out: %w", context.DeadlineExceeded)
+	}
+	return err
+}
+
+// syncOnExit commits the workbook and pushes it if configured, if sync
+// is enabled. It gets ExitSyncDeadline; a push that does not finish by
+// then is left for pushOnStart. A failure is logged and notified, but
+// never stops the app from exiting.
+func syncOnExit() {
+	if !Sync.Enabled {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ExitSyncDeadline)
+	defer cancel()
+
+	slog.Info("Syncing to git...")
+	if err := syncDataFile(ctx); err != nil {
+		slog.Error("Sync failed", "err", err)
+		notify("Sync failed", err.Error())
+		return
+	}
+	if !Sync.Push {
+		return
+	}
+	if err := pushRepository(ctx); errors.Is(err, context.DeadlineExceeded) {
+		slog.Warn("Push did not finish before exit, it is retried on the next start")
+	} else if err != nil {
+		slog.Error("Push failed", "err", err)
+		notify("Push failed", err.Error())
+	}
+}
+
+// pushOnStart pushes what earlier exits committed but could not push,
+// within Sync.Timeout. It runs in the background at startup.
+func pushOnStart() {
+	if !Sync.Enabled || !Sync.Push {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), Sync.Timeout)
+	defer cancel()
+
+	if err := pushRepository(ctx); err != nil {
+		slog.Warn("Push failed", "err", err)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestSyncDataFile(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("no git:", err)
+	}
+	oldDir, oldSync := flagDataDir, Sync
+	t.Cleanup(func() { flagDataDir, Sync = oldDir, oldSync })
+
+	repo := t.TempDir()
+	Sync = SyncConfig{Repository: repo, Timeout: Sync.Timeout}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.name", "Test"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "commit.gpgsign", "false"},
+	} {
+		if _, err := git(context.Background(), args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	flagDataDir = filepath.Join(repo, "data")
+	os.MkdirAll(flagDataDir, 0o755)
+	initExcelFile()
+	if err := syncDataFile(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := git(context.Background(), "ls-files"); err != nil || out != "data/"+excelFileName+"\n" {
+		t.Errorf("tracked files = %q, %v", out, err)
+	}
+
+	// A workbook outside the repository is not copied over a tracked
+	// file of the same name.
+	flagDataDir = t.TempDir()
+	initExcelFile()
+	tracked := filepath.Join(repo, excelFileName)
+	os.WriteFile(tracked, []byte("keep"), 0o644)
+	if err := syncDataFile(context.Background()); err == nil {
+		t.Error("synced a workbook outside the repository")
+	}
+	if data, _ := os.ReadFile(tracked); string(data) != "keep" {
+		t.Error("the file in the repository was overwritten")
+	}
+}
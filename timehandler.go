@@ -5,47 +5,65 @@ import (
 )
 
 func updateTimeDisplay() {
+	defer Wg.Done()
 	for {
 		if state.running && !state.paused {
 			Elapsed += WaitDuration
-			timeLabel.SetText(formatDuration(Elapsed))
+			if !pomodoro.enabled {
+				timeLabel.SetText(formatDuration(Elapsed))
+			}
+		}
+		tickPomodoro()
+		tickBudget()
+		tickTray()
+		tickServer()
+		checkIdle()
+		if !sleepOrDone(WaitDuration) {
+			return
 		}
-		time.Sleep(WaitDuration)
 	}
 }
 
 func startTimer() {
 	if !state.running {
+		before := snapshotTimer()
 		state.startTime = time.Now()
 		state.running = true
 		state.paused = false
-		logEvent("START")
+		resetPomodoro()
+		loadBudget()
+		pushAction(before, logEvent("START"))
 	}
 }
 
 func togglePause() {
 	if state.running {
+		before := snapshotTimer()
 		if state.paused {
 			state.paused = false
-			logEvent("RESUME")
+			pushAction(before, logEvent("RESUME"))
 		} else {
 			state.paused = true
-			logEvent("PAUSE")
+			pushAction(before, logEvent("PAUSE"))
 		}
 	}
 }
 
 func toggleStop() {
 	if state.running {
+		before := snapshotTimer()
 		state.running = false
 		state.paused = false
-		logEvent("STOP")
+		entry := logEvent("STOP")
 		timeLabel.SetText("00:00:00")
+		notesEntry.SetText("")
 		Elapsed = 0
+		resetPomodoro()
+		pushAction(before, entry)
 	}
 }
 
-func logEvent(eventType string) {
+func logEvent(eventType string) TimerEntry {
 	entry := TimerEntry{
 		Timestamp: time.Now(),
 		Event:     eventType,
@@ -54,10 +72,26 @@ func logEvent(eventType string) {
 
 	if eventType == "STOP" {
 		entry.Duration = Elapsed
+		entry.Notes = notesEntry.Text
 	} else if eventType == "EXIT" {
 		entry.Duration = Elapsed
+		entry.Notes = notesEntry.Text
 	}
 
+	recordEntry(entry)
+	return entry
+}
+
+// recordEntry saves an entry and runs the hooks and webhooks that follow
+// an event.
+func recordEntry(entry TimerEntry) {
 	state.entries = append(state.entries, entry)
 	saveToExcel(entry)
+	runHooks(entry)
+	// The STOP that ends a run still belongs to it.
+	session := ""
+	if state.running || entry.Event == "STOP" {
+		session = sessionID(state.startTime)
+	}
+	queueWebhooks(entry, session)
 }
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timewarrior keeps one interval per line in its data files:
+//
+//	inc 20250102T090000Z - 20250102T103000Z # Report docs project:Acme # "First draft"
+//
+// The first tag is the activity, other tags become #tags of its name and
+// a project:NAME tag is its project. The annotation after a second # is
+// the session's notes.
+
+const timewProjectPrefix = "project:"
+
+// splitTimewTags splits the tags after #, keeping quoted tags whole. A
+// bare # ends the tags and is followed by the annotation.
+func splitTimewTags(text string) (tags []string, annotation string, err error) {
+	for text = strings.TrimSpace(text); text != ""; text = strings.TrimSpace(text) {
+		if text[0] != '"' {
+			tag, rest, _ := strings.Cut(text, " ")
+			if tag == "#" {
+				annotation, err = unquoteTimewAnnotation(rest)
+				return tags, annotation, err
+			}
+			tags = append(tags, tag)
+			text = rest
+			continue
+		}
+
+		end := 1
+		for end < len(text) && text[end] != '"' {
+			if text[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(text) {
+			return nil, "", fmt.Errorf("unterminated tag %s", text)
+		}
+		tag, err := strconv.Unquote(text[:end+1])
+		if err != nil {
+			return nil, "", err
+		}
+		tags = append(tags, tag)
+		text = text[end+1:]
+	}
+	return tags, "", nil
+}
+
+// unquoteTimewAnnotation reads the annotation after the second #, which
+// Timewarrior writes quoted.
+func unquoteTimewAnnotation(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, `"`) {
+		return text, nil
+	}
+	annotation, err := strconv.Unquote(text)
+	if err != nil {
+		return "", fmt.Errorf("bad annotation %s: %w", text, err)
+	}
+	return annotation, nil
+}
+
+// quoteTimewTag quotes a tag that would not survive splitting.
+func quoteTimewTag(tag string) string {
+	if tag == "" || strings.ContainsAny(tag, " \t\"\\#") {
+		return strconv.Quote(tag)
+	}
+	return tag
+}
+
+// parseTimew reads the closed intervals of a data file. Open intervals,
+// still being tracked, are skipped.
+func parseTimew(r io.Reader) ([]importedSession, error) {
+	var sessions []importedSession
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "inc ") {
+			continue
+		}
+
+		times, tagText, _ := strings.Cut(strings.TrimPrefix(line, "inc "), "#")
+		fields := strings.Fields(times)
+		if len(fields) != 3 || fields[1] != "-" {
+			continue
+		}
+		start, err := time.Parse(icsTimeLayout, fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		end, err := time.Parse(icsTimeLayout, fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		tags, annotation, err := splitTimewTags(tagText)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+
+		session := importedSession{Session: Session{
+			Start:    start.Local(),
+			End:      end.Local(),
+			Duration: end.Sub(start),
+			Notes:    annotation,
+		}}
+		var words []string
+		for _, tag := range tags {
+			switch {
+			case strings.HasPrefix(tag, timewProjectPrefix):
+				session.Project = strings.TrimPrefix(tag, timewProjectPrefix)
+			case len(words) == 0:
+				words = append(words, tag)
+			default:
+				words = append(words, "#"+strings.ReplaceAll(tag, " ", "_"))
+			}
+		}
+		session.Name = strings.Join(words, " ")
+		sessions = append(sessions, session)
+	}
+	return sessions, scanner.Err()
+}
+
+// writeTimew writes sessions as Timewarrior intervals.
+func writeTimew(w io.Writer, sessions []Session) error {
+	projectFor := projectLookup()
+	out := bufio.NewWriter(w)
+	for _, session := range sessions {
+		var tags []string
+		var words []string
+		for _, word := range strings.Fields(session.Name) {
+			if len(word) > 1 && strings.HasPrefix(word, "#") {
+				tags = append(tags, quoteTimewTag(word[1:]))
+			} else {
+				words = append(words, word)
+			}
+		}
+		if len(words) > 0 {
+			tags = append([]string{quoteTimewTag(strings.Join(words, " "))}, tags...)
+		}
+		if project := projectFor(session.Name); project != "" {
+			tags = append(tags, quoteTimewTag(timewProjectPrefix+project))
+		}
+
+		line := fmt.Sprintf("inc %s - %s", session.Start.UTC().Format(icsTimeLayout), session.End.UTC().Format(icsTimeLayout))
+		if len(tags) > 0 || session.Notes != "" {
+			line += " #"
+		}
+		if len(tags) > 0 {
+			line += " " + strings.Join(tags, " ")
+		}
+		if session.Notes != "" {
+			line += " # " + strconv.Quote(session.Notes)
+		}
+		fmt.Fprintln(out, line)
+	}
+	return out.Flush()
+}
+
+// exportTimew writes the sessions of query as Timewarrior intervals.
+func exportTimew(w io.Writer, query sessionQuery) error {
+	sessions, err := filteredSessions(query)
+	if err != nil {
+		return err
+	}
+	return writeTimew(w, sessions)
+}
+
+// readTimewFiles reads the intervals of data files, oldest first.
+func readTimewFiles(names []string) ([]importedSession, error) {
+	var sessions []importedSession
+	for _, name := range names {
+		file, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		read, err := parseTimew(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		sessions = append(sessions, read...)
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].Start.Before(sessions[j].Start)
+	})
+	return sessions, nil
+}
+
+func exportTimewCommand(args []string) error {
+	flags := flag.NewFlagSet("export-timew", flag.ContinueOnError)
+	shared := sessionFilterFlags(flags, "")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	query, out, err := shared.open()
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return exportTimew(out, query)
+}
+
+func importTimewCommand(args []string) error {
+	flags := flag.NewFlagSet("import-timew", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "show what would be imported without writing")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return fmt.Errorf("usage: import-timew [flags] FILE...")
+	}
+
+	sessions, err := readTimewFiles(flags.Args())
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		previewSessions(os.Stdout, sessions)
+		return nil
+	}
+
+	added, skipped, err := importSessions(sessions)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d entries, skipped %d duplicates\n", added, skipped)
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTimew(t *testing.T) {
+	data := `inc 20240304T090000Z - 20240304T103000Z # Code "code review" project:Acme
+inc 20240304T110000Z
+inc 20240304T120000Z - 20240304T121500Z # Lunch
+inc 20240304T130000Z - 20240304T140000Z # Call project:Acme # "Agreed on #12, \"soon\""
+inc 20240304T150000Z - 20240304T151500Z # # "No tags"
+`
+	sessions, err := parseTimew(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 4 {
+		t.Fatalf("got %d sessions, want 4 without the open interval", len(sessions))
+	}
+
+	code := sessions[0]
+	if code.Name != "Code #code_review" || code.Project != "Acme" {
+		t.Errorf("first session = %+v", code)
+	}
+	if want := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC); !code.Start.Equal(want) || code.Duration != 90*time.Minute {
+		t.Errorf("first session runs %v for %v", code.Start, code.Duration)
+	}
+	if sessions[1].Name != "Lunch" {
+		t.Errorf("second session is named %q", sessions[1].Name)
+	}
+	if call := sessions[2]; call.Name != "Call" || call.Project != "Acme" || call.Notes != `Agreed on #12, "soon"` {
+		t.Errorf("annotated session = %+v", call)
+	}
+	if untagged := sessions[3]; untagged.Name != "" || untagged.Notes != "No tags" {
+		t.Errorf("session with only an annotation = %+v", untagged)
+	}
+}
+
+func TestTimewRoundTrip(t *testing.T) {
+	sessions := []Session{{
+		Name:     `Write "docs" #draft`,
+		Start:    time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC),
+		End:      time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC),
+		Duration: time.Hour,
+		Notes:    "Intro and \"setup\" # done",
+	}}
+	var buf bytes.Buffer
+	if err := writeTimew(&buf, sessions); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := parseTimew(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != 1 || read[0].Name != sessions[0].Name || read[0].Notes != sessions[0].Notes ||
+		!read[0].Start.Equal(sessions[0].Start) {
+		t.Errorf("read back %+v from %q", read, buf.String())
+	}
+}
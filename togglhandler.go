@@ -0,0 +1,239 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// trackerColumns names the columns of Toggl Track and Clockify detailed
+// reports. Each field lists the names both use.
+var trackerColumns = map[string][]string{
+	"description": {"Description"},
+	"task":        {"Task"},
+	"project":     {"Project"},
+	"client":      {"Client"},
+	"tags":        {"Tags"},
+	"start date":  {"Start date", "Start Date"},
+	"start time":  {"Start time", "Start Time"},
+	"end date":    {"End date", "End Date"},
+	"end time":    {"End time", "End Time"},
+	"duration":    {"Duration", "Duration (h)"},
+}
+
+// trackerDateFormats are the date formats reports are written in, by
+// the name -date-format takes. Clockify writes dates as the user's
+// settings say.
+var trackerDateFormats = []struct {
+	name   string
+	layout string
+}{
+	{"YYYY-MM-DD", "2006-01-02"},
+	{"MM/DD/YYYY", "01/02/2006"},
+	{"DD/MM/YYYY", "02/01/2006"},
+	{"DD.MM.YYYY", "02.01.2006"},
+}
+
+var trackerTimeLayouts = []string{"15:04:05", "15:04", "03:04:05 PM", "3:04:05 PM", "03:04 PM", "3:04 PM"}
+
+// trackerDateFormatNames lists the names of trackerDateFormats.
+func trackerDateFormatNames() []string {
+	var names []string
+	for _, format := range trackerDateFormats {
+		names = append(names, format.name)
+	}
+	return names
+}
+
+// trackerDateLayout finds the layout of a named date format. With no
+// name it finds the one format all dates parse with, and fails when
+// none or several do, as for 03/04/2024, rather than guess.
+func trackerDateLayout(name string, dates []string) (string, error) {
+	if name != "" {
+		for _, format := range trackerDateFormats {
+			if strings.EqualFold(format.name, name) {
+				return format.layout, nil
+			}
+		}
+		return "", fmt.Errorf("unknown date format %q, want one of %s", name,
+			strings.Join(trackerDateFormatNames(), ", "))
+	}
+
+	var matched []string
+	var layout string
+	for _, format := range trackerDateFormats {
+		fits := true
+		for _, date := range dates {
+			if _, err := time.Parse(format.layout, strings.TrimSpace(date)); err != nil {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			matched = append(matched, format.name)
+			layout = format.layout
+		}
+	}
+	switch {
+	case len(dates) == 0:
+		return trackerDateFormats[0].layout, nil
+	case len(matched) == 0:
+		return "", fmt.Errorf("unknown date format in %q, set the date format", dates[0])
+	case len(matched) > 1:
+		return "", fmt.Errorf("dates could be %s, set the date format", strings.Join(matched, " or "))
+	}
+	return layout, nil
+}
+
+// parseTrackerTime reads the separate date and time columns of a
+// report as local time.
+func parseTrackerTime(date, clock, dateLayout string) (time.Time, error) {
+	date = strings.TrimSpace(date)
+	clock = strings.ToUpper(strings.TrimSpace(clock))
+	for _, timeLayout := range trackerTimeLayouts {
+		if t, err := time.ParseInLocation(dateLayout+" "+timeLayout, date+" "+clock, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q %q", date, clock)
+}
+
+// trackerActivity names the activity after the description, else the
+// task or project, with the tags as #tags.
+func trackerActivity(description, task, project, tags string) string {
+	name := strings.TrimSpace(description)
+	if name == "" {
+		name = strings.TrimSpace(task)
+	}
+	if name == "" {
+		name = strings.TrimSpace(project)
+	}
+	for _, tag := range strings.Split(tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			name += " #" + strings.Join(strings.Fields(tag), "_")
+		}
+	}
+	return strings.TrimSpace(name)
+}
+
+// parseTrackerCSV reads the sessions of a Toggl Track or Clockify
+// detailed CSV report. dateFormat names one of trackerDateFormats, or
+// is empty to tell it from the dates.
+func parseTrackerCSV(r io.Reader, delimiter rune, dateFormat string) ([]importedSession, error) {
+	header, records, err := readCSV(r, delimiter)
+	if err != nil {
+		return nil, err
+	}
+
+	index := map[string]int{}
+	for field, names := range trackerColumns {
+		for i, column := range header {
+			for _, name := range names {
+				if strings.TrimSpace(column) == name {
+					if _, ok := index[field]; !ok {
+						index[field] = i
+					}
+				}
+			}
+		}
+	}
+	for _, field := range []string{"start date", "start time", "end date", "end time"} {
+		if _, ok := index[field]; !ok {
+			return nil, fmt.Errorf("no %q column, is this a Toggl or Clockify detailed report?", field)
+		}
+	}
+
+	value := func(record []string, field string) string {
+		i, ok := index[field]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return record[i]
+	}
+
+	var dates []string
+	for _, record := range records {
+		for _, field := range []string{"start date", "end date"} {
+			if date := strings.TrimSpace(value(record, field)); date != "" {
+				dates = append(dates, date)
+			}
+		}
+	}
+	dateLayout, err := trackerDateLayout(dateFormat, dates)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []importedSession
+	for n, record := range records {
+		line := n + 2
+		start, err := parseTrackerTime(value(record, "start date"), value(record, "start time"), dateLayout)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		end, err := parseTrackerTime(value(record, "end date"), value(record, "end time"), dateLayout)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		duration, err := parseFlexibleDuration(value(record, "duration"))
+		if err != nil || duration <= 0 {
+			duration = end.Sub(start)
+		}
+
+		sessions = append(sessions, importedSession{
+			Session: Session{
+				Name: trackerActivity(value(record, "description"), value(record, "task"),
+					value(record, "project"), value(record, "tags")),
+				Start:    start,
+				End:      end,
+				Duration: duration,
+			},
+			Project: strings.TrimSpace(value(record, "project")),
+			Client:  strings.TrimSpace(value(record, "client")),
+		})
+	}
+	return sessions, nil
+}
+
+func importTogglCommand(args []string) error {
+	flags := flag.NewFlagSet("import-toggl", flag.ContinueOnError)
+	delimiterText := flags.String("delimiter", ",", "field delimiter")
+	dateFormat := flags.String("date-format", "", "date format of the report: "+
+		strings.Join(trackerDateFormatNames(), ", ")+"; told from the dates if empty")
+	dryRun := flags.Bool("dry-run", false, "show what would be imported without writing")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: import-toggl [flags] FILE")
+	}
+
+	delimiter, err := parseDelimiter(*delimiterText)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	sessions, err := parseTrackerCSV(file, delimiter, *dateFormat)
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		previewSessions(os.Stdout, sessions)
+		return nil
+	}
+
+	added, skipped, err := importSessions(sessions)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d entries, skipped %d duplicates\n", added, skipped)
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const togglReport = `Description,Project,Client,Task,Tags,Start date,Start time,End date,End time,Duration
+Write report,Acme,Big Co,,"urgent, review",2024-03-04,09:00:00,2024-03-04,10:30:00,01:30:00
+,Internal,,Planning,,2024-03-04,13:00:00,2024-03-04,13:20:00,00:20:00
+`
+
+func TestParseTrackerCSV(t *testing.T) {
+	sessions, err := parseTrackerCSV(strings.NewReader(togglReport), ',', "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("got %d sessions, want 2", len(sessions))
+	}
+
+	first := sessions[0]
+	if first.Name != "Write report #urgent #review" || first.Project != "Acme" || first.Client != "Big Co" {
+		t.Errorf("first session = %+v", first)
+	}
+	if want := time.Date(2024, 3, 4, 9, 0, 0, 0, time.Local); !first.Start.Equal(want) {
+		t.Errorf("start = %v, want %v", first.Start, want)
+	}
+	if first.Duration != 90*time.Minute {
+		t.Errorf("duration = %v, want 1h30m", first.Duration)
+	}
+	if sessions[1].Name != "Planning" {
+		t.Errorf("second session is named %q, want the task", sessions[1].Name)
+	}
+}
+
+func TestParseTrackerCSVDateFormats(t *testing.T) {
+	report := func(date string) string {
+		return "Description;Start date;Start time;End date;End time\n" +
+			"Call;" + date + ";3:00 PM;" + date + ";4:00 PM\n"
+	}
+
+	if _, err := parseTrackerCSV(strings.NewReader(report("03/04/2024")), ';', ""); err == nil {
+		t.Error("ambiguous dates were guessed instead of failing")
+	}
+
+	tests := []struct {
+		date   string
+		format string
+		want   time.Time
+	}{
+		{"03/04/2024", "DD/MM/YYYY", time.Date(2024, 4, 3, 15, 0, 0, 0, time.Local)},
+		{"03/04/2024", "MM/DD/YYYY", time.Date(2024, 3, 4, 15, 0, 0, 0, time.Local)},
+		{"03/14/2024", "", time.Date(2024, 3, 14, 15, 0, 0, 0, time.Local)},
+		{"14.03.2024", "", time.Date(2024, 3, 14, 15, 0, 0, 0, time.Local)},
+	}
+	for _, test := range tests {
+		sessions, err := parseTrackerCSV(strings.NewReader(report(test.date)), ';', test.format)
+		if err != nil {
+			t.Errorf("%s as %q: %v", test.date, test.format, err)
+			continue
+		}
+		if !sessions[0].Start.Equal(test.want) {
+			t.Errorf("%s as %q starts %v, want %v", test.date, test.format, sessions[0].Start, test.want)
+		}
+	}
+
+	if _, err := parseTrackerCSV(strings.NewReader(report("2024-03-04")), ';', "YYYY/DD"); err == nil {
+		t.Error("unknown date format was accepted")
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+)
+
+var (
+	trayApp      desktop.App
+	trayMenu     *fyne.Menu
+	trayStatus   *fyne.MenuItem
+	trayPause    *fyne.MenuItem
+	trayRecent   *fyne.MenuItem
+	trayText     string
+	trayRecentAt time.Time
+	trayWindow   fyne.Window
+)
+
+const recentActivityCount = 5
+
+// setupTray adds a system tray icon with the timer controls when the
+// driver supports one. It reports whether the tray is available.
+func setupTray(window fyne.Window, quit func()) bool {
+	desk, ok := App.(desktop.App)
+	if !ok {
+		return false
+	}
+	trayApp = desk
+	trayWindow = window
+
+	trayStatus = fyne.NewMenuItem("Not running", nil)
+	trayStatus.Disabled = true
+	trayPause = fyne.NewMenuItem("Pause", pauseTimer)
+	trayRecent = fyne.NewMenuItem("Recent activities", nil)
+
+	showItem := fyne.NewMenuItem("Show window", func() {
+		window.Show()
+		window.RequestFocus()
+	})
+	quitItem := fyne.NewMenuItem("Quit", quit)
+	quitItem.IsQuit = true
+
+	trayMenu = fyne.NewMenu("Time Tracker",
+		trayStatus,
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Start", startTimer),
+		trayPause,
+		fyne.NewMenuItem("Stop", stopTimer),
+		fyne.NewMenuItemSeparator(),
+		trayRecent,
+		fyne.NewMenuItemSeparator(),
+		showItem,
+		quitItem,
+	)
+	refreshRecentActivities()
+
+	desk.SetSystemTrayIcon(ResourceIconPng)
+	desk.SetSystemTrayMenu(trayMenu)
+	return true
+}
+
+// hideToTray hides the main window; the timer keeps running and the tray
+// menu can show it again.
+func hideToTray() {
+	if trayWindow != nil {
+		trayWindow.Hide()
+	}
+}
+
+// tickTray is called from the update loop and refreshes the status line
+// of the tray menu when it changes.
+func tickTray() {
+	if trayMenu == nil {
+		return
+	}
+
+	text := "Not running"
+	pause := "Pause"
+	if state.running {
+		text = fmt.Sprintf("%s — %s", nameEntry.Text, formatDuration(Elapsed))
+		if state.paused {
+			text += " (paused)"
+			pause = "Resume"
+		}
+	}
+
+	if time.Since(trayRecentAt) > time.Minute {
+		refreshRecentActivities()
+		trayText = ""
+	}
+	if text == trayText && pause == trayPause.Label {
+		return
+	}
+
+	trayText = text
+	trayStatus.Label = text
+	trayPause.Label = pause
+	trayApp.SetSystemTrayMenu(trayMenu)
+}
+
+// refreshRecentActivities rebuilds the recent activities submenu from the
+// last week of the workbook, most recent first.
+func refreshRecentActivities() {
+	trayRecentAt = time.Now()
+
+	now := time.Now()
+	entries, _ := readEntries(now.AddDate(0, 0, -7), now)
+	entries = append(entries, state.entries...)
+
+	seen := map[string]bool{}
+	var items []*fyne.MenuItem
+	for i := len(entries) - 1; i >= 0 && len(items) < recentActivityCount; i-- {
+		name := entries[i].Name
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		items = append(items, fyne.NewMenuItem(name, func() {
+			startActivity(name)
+		}))
+	}
+
+	trayRecent.ChildMenu = fyne.NewMenu("", items...)
+	trayRecent.Disabled = len(items) == 0
+}
+
+// startActivity switches the timer to another activity, stopping the
+// current one first.
+func startActivity(name string) {
+	if state.running && nameEntry.Text != name {
+		toggleStop()
+	}
+	nameEntry.SetText(name)
+	startTimer()
+}
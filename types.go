@@ -18,17 +18,27 @@ type TimerState struct {
 	entries   []TimerEntry
 }
 
+type Activity struct {
+	Name         string
+	DailyBudget  time.Duration
+	WeeklyBudget time.Duration
+	Project      string
+	Client       string
+}
+
 type TimerEntry struct {
 	Timestamp time.Time
 	Event     string
 	Name      string
 	Duration  time.Duration
+	Notes     string
 }
 
 var (
 	state         TimerState
 	timeLabel     *widget.Label
 	nameEntry     *widget.Entry
+	notesEntry    *widget.Entry
 	excelFileName = "time_tracker.xlsx"
 )
 
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+)
+
+// timerSnapshot holds the parts of the timer that an action changes.
+type timerSnapshot struct {
+	startTime time.Time
+	running   bool
+	paused    bool
+	elapsed   time.Duration
+}
+
+// timerAction is one START, PAUSE, RESUME or STOP that can be undone.
+type timerAction struct {
+	entry  TimerEntry
+	before timerSnapshot
+	after  timerSnapshot
+}
+
+var (
+	undoStack []timerAction
+	redoStack []timerAction
+)
+
+func snapshotTimer() timerSnapshot {
+	return timerSnapshot{
+		startTime: state.startTime,
+		running:   state.running,
+		paused:    state.paused,
+		elapsed:   Elapsed,
+	}
+}
+
+// pushAction records an action after it has been applied. A new action
+// clears anything that could have been redone.
+func pushAction(before timerSnapshot, entry TimerEntry) {
+	undoStack = append(undoStack, timerAction{
+		entry:  entry,
+		before: before,
+		after:  snapshotTimer(),
+	})
+	redoStack = nil
+}
+
+// restoreTimer puts the timer back into snap. If the timer was counting
+// in snap, the time since the action happened is counted as well, so
+// undoing an accidental stop or pause does not lose the time in between.
+func restoreTimer(snap timerSnapshot, since time.Time) {
+	state.startTime = snap.startTime
+	state.running = snap.running
+	state.paused = snap.paused
+	Elapsed = snap.elapsed
+	if snap.running && !snap.paused {
+		Elapsed += time.Since(since)
+	}
+	timeLabel.SetText(formatDuration(Elapsed))
+}
+
+func undoTimer() {
+	if len(undoStack) == 0 {
+		slog.Info("Nothing to undo.")
+		return
+	}
+
+	action := undoStack[len(undoStack)-1]
+	undoStack = undoStack[:len(undoStack)-1]
+
+	restoreTimer(action.before, action.entry.Timestamp)
+	removeEntry(action.entry)
+	if err := removeFromExcel(action.entry); err != nil {
+		slog.Error("Error undoing "+action.entry.Event, "err", err)
+	} else {
+		slog.Info("Undid " + action.entry.Event)
+	}
+
+	redoStack = append(redoStack, action)
+}
+
+func redoTimer() {
+	if len(redoStack) == 0 {
+		slog.Info("Nothing to redo.")
+		return
+	}
+
+	action := redoStack[len(redoStack)-1]
+	redoStack = redoStack[:len(redoStack)-1]
+
+	restoreTimer(action.after, action.entry.Timestamp)
+	state.entries = append(state.entries, action.entry)
+	saveToExcel(action.entry)
+
+	undoStack = append(undoStack, action)
+}
+
+// removeEntry drops the most recent in-memory copy of entry.
+func removeEntry(entry TimerEntry) {
+	for i := len(state.entries) - 1; i >= 0; i-- {
+		if state.entries[i].Timestamp.Equal(entry.Timestamp) && state.entries[i].Event == entry.Event {
+			state.entries = append(state.entries[:i], state.entries[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,458 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Webhook posts a JSON payload to URL when an event is recorded. Event
+// is an event type such as STOP, or * for every event. With a Secret the
+// body is signed with HMAC-SHA256 in the X-Timer-Signature header.
+type Webhook struct {
+	Event  string `toml:"event"`
+	URL    string `toml:"url"`
+	Secret string `toml:"secret"`
+}
+
+var (
+	Webhooks []Webhook
+
+	// WebhookTimeout limits one delivery attempt.
+	WebhookTimeout = 10 * time.Second
+	// WebhookRetryMin is the wait after a failed attempt. It doubles
+	// after every further failure, up to WebhookRetryMax.
+	WebhookRetryMin = 5 * time.Second
+	WebhookRetryMax = time.Hour
+
+	outboxMu   sync.Mutex
+	outboxKick = make(chan struct{}, 1)
+)
+
+// webhookPayload is the body posted to a webhook.
+type webhookPayload struct {
+	Event     string `json:"event"`
+	Activity  string `json:"activity"`
+	Timestamp string `json:"timestamp"`
+	Duration  int64  `json:"duration"`
+	SessionID string `json:"session_id"`
+}
+
+// delivery is a payload waiting in the outbox. Hook is the index of the
+// webhook in the config it was queued for.
+type delivery struct {
+	ID          string          `json:"id"`
+	URL         string          `json:"url"`
+	Hook        int             `json:"hook"`
+	Body        json.RawMessage `json:"body"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"next_attempt"`
+	LastError   string          `json:"last_error,omitempty"`
+}
+
+// outboxDir holds deliveries until their endpoint accepts them, so they
+// survive the endpoint being down and the app being closed. A delivery
+// being sent is renamed to .json.sending, so the app and a CLI command
+// sharing the outbox never both send it.
+func outboxDir() string {
+	return filepath.Join(dataDir(), "outbox")
+}
+
+func deliveryPath(id string) string {
+	return filepath.Join(outboxDir(), id+".json")
+}
+
+// claimDelivery takes a delivery for this process. It reports false when
+// another process took it first.
+func claimDelivery(id string) (string, bool) {
+	claimed := deliveryPath(id) + ".sending"
+	if err := os.Rename(deliveryPath(id), claimed); err != nil {
+		return "", false
+	}
+	now := time.Now()
+	os.Chtimes(claimed, now, now)
+	return claimed, true
+}
+
+// releaseStaleClaims puts back deliveries a process claimed and never
+// finished, because it died while sending.
+func releaseStaleClaims() {
+	files, err := filepath.Glob(filepath.Join(outboxDir(), "*.json.sending"))
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil && time.Since(info.ModTime()) > 2*WebhookTimeout+time.Minute {
+			os.Rename(file, strings.TrimSuffix(file, ".sending"))
+		}
+	}
+}
+
+// sessionID names the timer run an event belongs to after its start.
+// Events outside a run have none.
+func sessionID(start time.Time) string {
+	if start.IsZero() {
+		return ""
+	}
+	return strconv.FormatInt(start.UnixMilli(), 10)
+}
+
+func webhookBody(entry TimerEntry, session string) ([]byte, error) {
+	return json.Marshal(webhookPayload{
+		Event:     entry.Event,
+		Activity:  entry.Name,
+		Timestamp: entry.Timestamp.Format(time.RFC3339),
+		Duration:  int64(entry.Duration.Seconds()),
+		SessionID: session,
+	})
+}
+
+// queueWebhooks writes a delivery to the outbox for every webhook that
+// wants entry, then wakes the delivery loop.
+func queueWebhooks(entry TimerEntry, session string) {
+	body, err := webhookBody(entry, session)
+	if err != nil {
+		slog.Error("Error encoding webhook payload", "err", err)
+		return
+	}
+
+	queued := false
+	for i, hook := range Webhooks {
+		if hook.Event != "*" && hook.Event != entry.Event {
+			continue
+		}
+		if _, err := queueDelivery(i, hook.URL, body); err != nil {
+			slog.Error("Error queueing webhook", "url", hook.URL, "err", err)
+			continue
+		}
+		queued = true
+	}
+
+	if queued {
+		select {
+		case outboxKick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// queueDelivery queues body for webhook n and returns the delivery ID.
+func queueDelivery(n int, url string, body []byte) (string, error) {
+	d := delivery{
+		ID:          fmt.Sprintf("%d-%d", time.Now().UnixNano(), n),
+		URL:         url,
+		Hook:        n,
+		Body:        body,
+		NextAttempt: time.Now(),
+	}
+	return d.ID, writeDelivery(d)
+}
+
+func writeDelivery(d delivery) error {
+	if err := os.MkdirAll(outboxDir(), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	// Write then rename, so a crash never leaves half a delivery.
+	path := deliveryPath(d.ID)
+	if err := os.WriteFile(path+".tmp", data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func readOutbox() []delivery {
+	files, err := filepath.Glob(filepath.Join(outboxDir(), "*.json"))
+	if err != nil {
+		return nil
+	}
+	sort.Strings(files)
+
+	var deliveries []delivery
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var d delivery
+		if err := json.Unmarshal(data, &d); err != nil {
+			slog.Warn("Dropping unreadable webhook delivery", "file", file, "err", err)
+			os.Remove(file)
+			continue
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries
+}
+
+// signBody returns the HMAC-SHA256 of body as sha256=HEX.
+func signBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookSecret looks up the secret of the delivery's webhook when
+// sending, so it is never written to the outbox. A webhook that has moved
+// in the config is found by its URL. ok is false when the webhook is
+// gone or has changed its URL.
+func webhookSecret(d delivery) (secret string, ok bool) {
+	if d.Hook >= 0 && d.Hook < len(Webhooks) && Webhooks[d.Hook].URL == d.URL {
+		return Webhooks[d.Hook].Secret, true
+	}
+	for _, hook := range Webhooks {
+		if hook.URL == d.URL {
+			return hook.Secret, true
+		}
+	}
+	return "", false
+}
+
+// errPermanent marks a delivery the endpoint will never accept.
+var errPermanent = errors.New("rejected")
+
+func postDelivery(ctx context.Context, d delivery) error {
+	ctx, cancel := context.WithTimeout(ctx, WebhookTimeout)
+	defer cancel()
+
+	// Never post unsigned what a webhook with a secret queued.
+	secret, ok := webhookSecret(d)
+	if !ok {
+		return fmt.Errorf("%w: no webhook for %s in the config", errPermanent, d.URL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errPermanent, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "timer")
+	req.Header.Set("X-Timer-Delivery", d.ID)
+	if secret != "" {
+		req.Header.Set("X-Timer-Signature", signBody(secret, d.Body))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("status %s", resp.Status)
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return fmt.Errorf("%w: status %s", errPermanent, resp.Status)
+	default:
+		return fmt.Errorf("status %s", resp.Status)
+	}
+}
+
+// retryDelay is the wait after the given number of failed attempts.
+func retryDelay(attempts int) time.Duration {
+	delay := WebhookRetryMin
+	for i := 1; i < attempts && delay < WebhookRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, WebhookRetryMax)
+}
+
+// deliverWebhooks makes one pass over the outbox, sending what is due,
+// until ctx ends. Deliveries that fail stay queued with a later attempt;
+// ones the endpoint rejects for good are dropped. It returns the outcome
+// of every delivery it sent by ID, nil for delivered.
+func deliverWebhooks(ctx context.Context) map[string]error {
+	outboxMu.Lock()
+	defer outboxMu.Unlock()
+
+	releaseStaleClaims()
+	results := map[string]error{}
+	for _, d := range readOutbox() {
+		if ctx.Err() != nil {
+			break
+		}
+		if time.Now().Before(d.NextAttempt) {
+			continue
+		}
+		claimed, ok := claimDelivery(d.ID)
+		if !ok {
+			continue
+		}
+
+		err := postDelivery(ctx, d)
+		switch {
+		case err != nil && ctx.Err() != nil:
+			// Cut short by shutdown, which is not the endpoint's fault.
+			os.Rename(claimed, deliveryPath(d.ID))
+			continue
+		case err == nil:
+			slog.Debug("Webhook delivered", "url", d.URL, "attempts", d.Attempts+1)
+		case errors.Is(err, errPermanent):
+			slog.Warn("Webhook rejected, dropping it", "url", d.URL, "err", err)
+		default:
+			d.Attempts++
+			d.NextAttempt = time.Now().Add(retryDelay(d.Attempts))
+			d.LastError = err.Error()
+			slog.Warn("Webhook failed, will retry", "url", d.URL, "attempts", d.Attempts, "err", err)
+			if err := writeDelivery(d); err != nil {
+				slog.Error("Error updating webhook delivery", "err", err)
+			}
+		}
+		os.Remove(claimed)
+		results[d.ID] = err
+	}
+	return results
+}
+
+// doneContext returns a context that ends when the app shuts down.
+func doneContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// runWebhooks delivers the outbox in the background, when an event is
+// queued and every WebhookRetryMin for retries. Shutdown cuts a pass
+// short.
+func runWebhooks() {
+	defer Wg.Done()
+	ctx, cancel := doneContext()
+	defer cancel()
+	for {
+		deliverWebhooks(ctx)
+		select {
+		case <-done:
+			return
+		case <-outboxKick:
+		case <-time.After(WebhookRetryMin):
+		}
+	}
+}
+
+// flushWebhooks gives the outbox one last pass on exit, until deadline.
+// Whatever is left is sent on the next start.
+func flushWebhooks(deadline time.Time) {
+	if len(Webhooks) == 0 {
+		return
+	}
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	deliverWebhooks(ctx)
+}
+
+// webhookTestCommand queues a TEST event for every webhook, makes one
+// delivery pass over the outbox and reports how the TEST events went.
+func webhookTestCommand(args []string) error {
+	flags := flag.NewFlagSet("webhook-test", flag.ContinueOnError)
+	activity := flags.String("activity", "Webhook test", "activity name in the payload")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if len(Webhooks) == 0 {
+		return fmt.Errorf("no webhooks configured")
+	}
+
+	now := time.Now()
+	body, err := webhookBody(TimerEntry{Timestamp: now, Event: "TEST", Name: *activity}, sessionID(now))
+	if err != nil {
+		return err
+	}
+	ids := make([]string, len(Webhooks))
+	for i, hook := range Webhooks {
+		if ids[i], err = queueDelivery(i, hook.URL, body); err != nil {
+			return err
+		}
+	}
+	results := deliverWebhooks(context.Background())
+
+	failed := 0
+	for i, hook := range Webhooks {
+		err, sent := results[ids[i]]
+		switch {
+		case !sent:
+			fmt.Printf("%s: queued, the running app sends it\n", hook.URL)
+		case err == nil:
+			fmt.Printf("%s: delivered\n", hook.URL)
+		case errors.Is(err, errPermanent):
+			fmt.Printf("%s: %v, dropped\n", hook.URL, err)
+			failed++
+		default:
+			fmt.Printf("%s: %v, will retry from %s\n", hook.URL, err, outboxDir())
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d deliveries failed", failed, len(Webhooks))
+	}
+	return nil
+}
+
+// webhookListenCommand is a local stand-in for a webhook endpoint. It
+// prints what it receives and checks the signature when given a secret.
+func webhookListenCommand(args []string) error {
+	flags := flag.NewFlagSet("webhook-listen", flag.ContinueOnError)
+	address := flags.String("addr", "127.0.0.1:8378", "address to listen on")
+	secret := flags.String("secret", "", "secret to check signatures with")
+	status := flags.Int("status", http.StatusOK, "status to answer with, to try retries")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Addr: *address, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		signature := r.Header.Get("X-Timer-Signature")
+		check := ""
+		if *secret != "" {
+			check = " signature invalid"
+			if hmac.Equal([]byte(signature), []byte(signBody(*secret, body))) {
+				check = " signature ok"
+			}
+		}
+		fmt.Printf("%s %s %s%s\n%s\n", time.Now().Format(time.TimeOnly), r.Method,
+			r.Header.Get("X-Timer-Delivery"), check, strings.TrimSpace(string(body)))
+		w.WriteHeader(*status)
+	})}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	fmt.Printf("Listening on http://%s\n", *address)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// testOutbox points the data directory at a temporary one and configures
+// one webhook for url.
+func testOutbox(t *testing.T, url string) {
+	t.Helper()
+	oldDir, oldHooks := flagDataDir, Webhooks
+	flagDataDir = t.TempDir()
+	Webhooks = []Webhook{{Event: "*", URL: url, Secret: "s3cret"}}
+	t.Cleanup(func() {
+		flagDataDir, Webhooks = oldDir, oldHooks
+	})
+}
+
+func TestDeliverWebhooks(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		wantErr   bool
+		permanent bool
+		wantLeft  int
+	}{
+		{"accepted", http.StatusNoContent, false, false, 0},
+		{"server error", http.StatusBadGateway, true, false, 1},
+		{"too many requests", http.StatusTooManyRequests, true, false, 1},
+		{"rejected", http.StatusNotFound, true, true, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				body, _ := io.ReadAll(r.Body)
+				if got, want := r.Header.Get("X-Timer-Signature"), signBody("s3cret", body); got != want {
+					t.Errorf("signature = %q, want %q", got, want)
+				}
+				w.WriteHeader(test.status)
+			}))
+			defer server.Close()
+			testOutbox(t, server.URL)
+
+			id, err := queueDelivery(0, server.URL, []byte(`{"event":"TEST"}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			results := deliverWebhooks(context.Background())
+			err, sent := results[id]
+			if !sent {
+				t.Fatal("delivery was not sent")
+			}
+			if (err != nil) != test.wantErr || errors.Is(err, errPermanent) != test.permanent {
+				t.Errorf("err = %v", err)
+			}
+
+			left := readOutbox()
+			if len(left) != test.wantLeft {
+				t.Fatalf("%d deliveries left, want %d", len(left), test.wantLeft)
+			}
+			if test.wantLeft == 0 {
+				return
+			}
+
+			// A failed delivery waits for its backoff before the next try.
+			d := left[0]
+			if d.Attempts != 1 || d.LastError == "" {
+				t.Errorf("delivery after failure = %+v", d)
+			}
+			if wait := time.Until(d.NextAttempt); wait < retryDelay(1)-time.Second || wait > retryDelay(1) {
+				t.Errorf("next attempt in %s, want %s", wait, retryDelay(1))
+			}
+			deliverWebhooks(context.Background())
+			if requests != 1 {
+				t.Errorf("%d requests, want 1 before the backoff ends", requests)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, WebhookRetryMin},
+		{2, 2 * WebhookRetryMin},
+		{3, 4 * WebhookRetryMin},
+		{100, WebhookRetryMax},
+	}
+	for _, test := range tests {
+		if got := retryDelay(test.attempts); got != test.want {
+			t.Errorf("retryDelay(%d) = %s, want %s", test.attempts, got, test.want)
+		}
+	}
+}
+
+func TestSignBody(t *testing.T) {
+	// The HMAC-SHA256 example from Wikipedia.
+	got := signBody("key", []byte("The quick brown fox jumps over the lazy dog"))
+	want := "sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"
+	if got != want {
+		t.Errorf("signBody = %s, want %s", got, want)
+	}
+}
+
+func TestClaimDelivery(t *testing.T) {
+	testOutbox(t, "http://127.0.0.1:1")
+	id, err := queueDelivery(0, Webhooks[0].URL, []byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claimed, ok := claimDelivery(id)
+	if !ok {
+		t.Fatal("first claim failed")
+	}
+	if _, ok := claimDelivery(id); ok {
+		t.Error("a claimed delivery was claimed again")
+	}
+	if len(readOutbox()) != 0 {
+		t.Error("a claimed delivery is still in the outbox")
+	}
+
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(claimed, old, old)
+	releaseStaleClaims()
+	if len(readOutbox()) != 1 {
+		t.Error("a stale claim was not put back")
+	}
+}
+
+func TestDeliverWebhooksRemovedHook(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+	testOutbox(t, server.URL)
+
+	id, err := queueDelivery(0, server.URL, []byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The webhook's URL changed in the config after the event was queued.
+	Webhooks = []Webhook{{Event: "*", URL: server.URL + "/new", Secret: "s3cret"}}
+
+	if err := deliverWebhooks(context.Background())[id]; !errors.Is(err, errPermanent) {
+		t.Errorf("err = %v, want the delivery dropped", err)
+	}
+	if requests != 0 {
+		t.Errorf("%d unsigned requests sent", requests)
+	}
+	if len(readOutbox()) != 0 {
+		t.Error("the delivery is still queued")
+	}
+}
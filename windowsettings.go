@@ -9,8 +9,8 @@ import (
 func windowMaker(myApp fyne.App, name string) fyne.Window {
 
 	window := myApp.NewWindow(name)
-	window.Resize(fyne.NewSize(400, 300))
-	window.SetFixedSize(true)
+	window.Resize(fyne.NewSize(WindowWidth, WindowHeight))
+	window.SetFixedSize(FixedSize)
 
 	// Create draggable header
 	//header := newDraggableHeader(window)